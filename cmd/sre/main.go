@@ -26,6 +26,9 @@ func main() {
 		cli.OncallCmd,
 		cli.NewNotificationsCmd(cfg),
 		cli.NewIncidentsCmd(cfg),
+		cli.NewTeamsCmd(cfg),
+		cli.NewServicesCmd(cfg),
+		cli.NewMaintenanceCmd(cfg),
 	)
 	if err != nil {
 		logrus.Fatalf("Failed to initialize root command: %v", err)
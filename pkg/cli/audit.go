@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+// auditSeverity is how serious an audit finding is.
+type auditSeverity int
+
+const (
+	auditInfo auditSeverity = iota
+	auditWarning
+	auditError
+)
+
+func (s auditSeverity) String() string {
+	switch s {
+	case auditInfo:
+		return "INFO"
+	case auditWarning:
+		return "WARNING"
+	case auditError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("auditSeverity(%d)", int(s))
+	}
+}
+
+// colored returns the severity padded to a fixed width and colored.
+func (s auditSeverity) colored() string {
+	padded := fmt.Sprintf("%-7s", s)
+	switch s {
+	case auditError:
+		return color.RedString(padded)
+	case auditWarning:
+		return color.YellowString(padded)
+	default:
+		return color.CyanString(padded)
+	}
+}
+
+// auditFinding is a problem found by an audit. Subject is what the finding is
+// about, e.g. an escalation policy or a user.
+type auditFinding struct {
+	Severity auditSeverity
+	Subject  string
+	Message  string
+}
+
+// printAuditFindings prints the findings, most severe first, followed by a
+// summary. It returns an error if any finding is at least as severe as
+// failOn, so that audits can be used in CI.
+func printAuditFindings(findings []auditFinding, failOn auditSeverity) error {
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity > findings[j].Severity
+	})
+	counts := make(map[auditSeverity]int)
+	failed := 0
+	for _, f := range findings {
+		fmt.Printf("%s %s: %s\n", f.Severity.colored(), f.Subject, f.Message)
+		counts[f.Severity]++
+		if f.Severity >= failOn {
+			failed++
+		}
+	}
+	if len(findings) == 0 {
+		fmt.Printf("No findings\n")
+		return nil
+	}
+	fmt.Printf("\n%d findings: %d errors, %d warnings, %d info\n", len(findings), counts[auditError], counts[auditWarning], counts[auditInfo])
+	if failed > 0 {
+		return fmt.Errorf("audit failed: %d findings with severity %s or higher", failed, failOn)
+	}
+	return nil
+}
@@ -45,36 +45,9 @@ func NewIncidentsCmd(cfg *config.Config) *cobra.Command {
 			}
 			ctx := context.Background()
 			client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
-			teamsMap := make(map[string]*pagerduty.Team, 0)
-			for _, teamStr := range cfg.PagerDuty.Teams {
-				if teamStr == "" {
-					continue
-				}
-				if teamStr[0] != '+' {
-					// this is a team name, not a team ID
-					// fetch the team ID
-					// TODO remove duplicates before fetching teams via API
-					opts := pagerduty.ListTeamOptions{
-						Query: teamStr,
-					}
-					resp, err := client.ListTeamsWithContext(ctx, opts)
-					if err != nil {
-						logrus.Warningf("Failed to get team with pattern %q, skipping. Error was: %v", teamStr, err)
-					}
-					for _, team := range resp.Teams {
-						teamsMap[team.ID] = &team
-					}
-				} else {
-					teamID := teamStr[1:]
-					teamsMap[teamID] = nil
-				}
-			}
-			teamIDs := make([]string, 0, len(teamsMap))
-			for teamID := range teamsMap {
-				teamIDs = append(teamIDs, teamID)
-			}
-			if len(teamIDs) == 0 {
-				logrus.Fatalf("No teams found")
+			teamIDs, err := resolveTeamIDs(ctx, client, cfg.PagerDuty.Teams)
+			if err != nil {
+				logrus.Fatalf("%v", err)
 			}
 			opts := pagerduty.ListIncidentsOptions{
 				Since:   start.Format(time.RFC3339),
@@ -94,6 +67,10 @@ func NewIncidentsCmd(cfg *config.Config) *cobra.Command {
 				}
 				opts.Offset += 1
 			}
+			maintenance, err := ongoingMaintenance(ctx, client, teamIDs)
+			if err != nil {
+				logrus.Warningf("%v", err)
+			}
 			for _, incident := range allIncidents {
 				createdAt, err := pagerParseTime(incident.CreatedAt)
 				if err != nil {
@@ -123,6 +100,9 @@ func NewIncidentsCmd(cfg *config.Config) *cobra.Command {
 					fmt.Printf("     Not resolved\n")
 				}
 				fmt.Printf("     Service: %s\n", ansi.ToURL(incident.Service.Summary, incident.Service.HTMLURL))
+				if mw, ok := maintenance[incident.Service.ID]; ok {
+					fmt.Printf("     Service is %s\n", maintenanceWarning(mw, loc))
+				}
 				fmt.Printf("     Last changed by: %s\n", ansi.ToURL(incident.LastStatusChangeBy.Summary, incident.LastStatusChangeBy.HTMLURL))
 				fmt.Printf("     Trigger: %s\n", incident.FirstTriggerLogEntry.Summary)
 				var teams []string
@@ -0,0 +1,390 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/ansi"
+	"github.com/insomniacslk/sre/pkg/config"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	str2duration "github.com/xhit/go-str2duration/v2"
+)
+
+func NewMaintenanceCmd(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "maintenance",
+		Aliases: []string{"mw"},
+		Short:   "Manage maintenance windows (PagerDuty)",
+		Long: `Manage maintenance windows. While a service is in maintenance, its incidents
+do not notify anybody.
+
+Services are resolved by ID, by the name or component of an ` + "`oncall.shortlist`" + `
+entry (every service using the entry's escalation policy or schedules),
+or by a query matching the names of the services of the configured teams.`,
+		Args: cobra.MinimumNArgs(1),
+	}
+	cmd.AddCommand(
+		newMaintenanceCreateCmd(cfg),
+		newMaintenanceListCmd(cfg),
+		newMaintenanceEndCmd(cfg),
+	)
+	return cmd
+}
+
+func newMaintenanceCreateCmd(cfg *config.Config) *cobra.Command {
+	var (
+		flagServices    []string
+		flagStart       string
+		flagDuration    string
+		flagDescription string
+		flagYes         bool
+	)
+	cmd := &cobra.Command{
+		Use:   "create --service <service> --duration <duration> --description <text>",
+		Short: "Put services in maintenance",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logrus.Debugf("Running maintenance create command")
+			ctx := context.Background()
+			if len(flagServices) == 0 {
+				return fmt.Errorf("no service specified, use --service")
+			}
+			if flagDescription == "" {
+				return fmt.Errorf("no description specified, use --description")
+			}
+			duration, err := str2duration.ParseDuration(flagDuration)
+			if err != nil {
+				return fmt.Errorf("failed to parse duration %q: %w", flagDuration, err)
+			}
+			if duration <= 0 {
+				return fmt.Errorf("duration must be positive")
+			}
+			loc, err := time.LoadLocation(cfg.Timezone)
+			if err != nil {
+				return fmt.Errorf("cannot load timezone %q: %w", cfg.Timezone, err)
+			}
+			start := time.Now().In(loc)
+			if flagStart != "" {
+				t, _, err := parseOverrideDateString(flagStart, loc)
+				if err != nil {
+					return fmt.Errorf("failed to parse --start: %w", err)
+				}
+				start = t.In(loc)
+			}
+			end := start.Add(duration)
+
+			client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+			services, err := resolveMaintenanceServices(ctx, client, cfg, flagServices)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Maintenance from %s to %s (%s): %s\n", start.Format(shiftTimeFormat), end.Format(shiftTimeFormat), duration, flagDescription)
+			for _, s := range services {
+				fmt.Printf("    %s [ID: %s]\n", ansi.ToURL(s.Summary, s.HTMLURL), s.ID)
+			}
+			if !flagYes {
+				ok, err := confirm(fmt.Sprintf("Do you want to put the above %d services in maintenance?", len(services)))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Printf("\nAborting\n")
+					return nil
+				}
+			}
+			me, err := client.GetCurrentUserWithContext(ctx, pagerduty.GetCurrentUserOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get the current user: %w", err)
+			}
+			mw, err := client.CreateMaintenanceWindowWithContext(ctx, me.Email, pagerduty.MaintenanceWindow{
+				StartTime:   start.Format(time.RFC3339),
+				EndTime:     end.Format(time.RFC3339),
+				Description: flagDescription,
+				Services:    services,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create maintenance window: %w", err)
+			}
+			fmt.Printf("Maintenance window %s created, end it early with `maintenance end %s`\n", ansi.ToURL(mw.ID, mw.HTMLURL), mw.ID)
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&flagServices, "service", "s", nil, "Service to put in maintenance (ID, shortlist component or name), can be repeated")
+	cmd.Flags().StringVar(&flagStart, "start", "", "Start of the maintenance, in any format accepted by the override command (default: now)")
+	cmd.Flags().StringVarP(&flagDuration, "duration", "d", "1h", "Duration of the maintenance")
+	cmd.Flags().StringVarP(&flagDescription, "description", "m", "", "Description of the maintenance")
+	cmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "Do not ask for confirmation")
+	return cmd
+}
+
+func newMaintenanceListCmd(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the ongoing and future maintenance windows of the configured teams",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logrus.Debugf("Running maintenance list command")
+			ctx := context.Background()
+			loc, err := time.LoadLocation(cfg.Timezone)
+			if err != nil {
+				return fmt.Errorf("cannot load timezone %q: %w", cfg.Timezone, err)
+			}
+			client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+			teamIDs, err := resolveTeamIDs(ctx, client, cfg.PagerDuty.Teams)
+			if err != nil {
+				return err
+			}
+			for _, filter := range []string{"ongoing", "future"} {
+				windows, err := listMaintenanceWindows(ctx, client, pagerduty.ListMaintenanceWindowsOptions{TeamIDs: teamIDs, Filter: filter})
+				if err != nil {
+					return fmt.Errorf("failed to list %s maintenance windows: %w", filter, err)
+				}
+				fmt.Printf("%s\n", ansi.Bold(fmt.Sprintf("%s%s (%d)", strings.ToUpper(filter[:1]), filter[1:], len(windows))))
+				for _, mw := range windows {
+					fmt.Printf("    %s [ID: %s] %s\n", ansi.ToURL(formatMaintenanceWindow(mw, loc), mw.HTMLURL), mw.ID, mw.Description)
+					for _, s := range mw.Services {
+						fmt.Printf("        %s\n", ansi.ToURL(s.Summary, s.HTMLURL))
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func newMaintenanceEndCmd(cfg *config.Config) *cobra.Command {
+	var flagYes bool
+	cmd := &cobra.Command{
+		Use:   "end <id>",
+		Short: "End an ongoing maintenance window, or delete a future one",
+		Long: `End an ongoing maintenance window, or delete a future one. Deleting a future
+maintenance window cannot be undone.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logrus.Debugf("Running maintenance end command")
+			ctx := context.Background()
+			loc, err := time.LoadLocation(cfg.Timezone)
+			if err != nil {
+				return fmt.Errorf("cannot load timezone %q: %w", cfg.Timezone, err)
+			}
+			client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+			mw, err := client.GetMaintenanceWindowWithContext(ctx, args[0], pagerduty.GetMaintenanceWindowOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get maintenance window %q: %w", args[0], err)
+			}
+			started, err := maintenanceStarted(*mw, time.Now())
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Maintenance %s [ID: %s]: %s\n", ansi.ToURL(formatMaintenanceWindow(*mw, loc), mw.HTMLURL), mw.ID, mw.Description)
+			for _, s := range mw.Services {
+				fmt.Printf("    %s [ID: %s]\n", ansi.ToURL(s.Summary, s.HTMLURL), s.ID)
+			}
+			action, done := "delete this future", "deleted"
+			if started {
+				action, done = "end this ongoing", "ended"
+			}
+			if !flagYes {
+				ok, err := confirm(fmt.Sprintf("Do you want to %s maintenance window?", action))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Printf("\nAborting\n")
+					return nil
+				}
+			}
+			if err := client.DeleteMaintenanceWindowWithContext(ctx, mw.ID); err != nil {
+				return fmt.Errorf("failed to end maintenance window %q: %w", mw.ID, err)
+			}
+			fmt.Printf("Maintenance window %s %s\n", mw.ID, done)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "Do not ask for confirmation")
+	return cmd
+}
+
+// maintenanceStarted reports whether a maintenance window is ongoing at the
+// given time rather than in the future. Windows that are already over cannot
+// be ended.
+func maintenanceStarted(mw pagerduty.MaintenanceWindow, now time.Time) (bool, error) {
+	start, err := time.Parse(time.RFC3339, mw.StartTime)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse start time %q of maintenance window %q: %w", mw.StartTime, mw.ID, err)
+	}
+	end, err := time.Parse(time.RFC3339, mw.EndTime)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse end time %q of maintenance window %q: %w", mw.EndTime, mw.ID, err)
+	}
+	if !now.Before(end) {
+		return false, fmt.Errorf("maintenance window %q is already over", mw.ID)
+	}
+	return !now.Before(start), nil
+}
+
+// formatMaintenanceWindow formats the time range of a maintenance window in
+// loc.
+func formatMaintenanceWindow(mw pagerduty.MaintenanceWindow, loc *time.Location) string {
+	start, err := time.Parse(time.RFC3339, mw.StartTime)
+	if err != nil {
+		return mw.StartTime + " - " + mw.EndTime
+	}
+	end, err := time.Parse(time.RFC3339, mw.EndTime)
+	if err != nil {
+		return mw.StartTime + " - " + mw.EndTime
+	}
+	return fmt.Sprintf("%s - %s", start.In(loc).Format(shiftTimeFormat), end.In(loc).Format(shiftTimeFormat))
+}
+
+// listMaintenanceWindows fetches all the maintenance windows matching opts,
+// following pagination.
+func listMaintenanceWindows(ctx context.Context, client *pagerduty.Client, opts pagerduty.ListMaintenanceWindowsOptions) ([]pagerduty.MaintenanceWindow, error) {
+	if opts.Limit == 0 {
+		opts.Limit = 100 // 100 is the maximum allowed by PagerDuty's API
+	}
+	var windows []pagerduty.MaintenanceWindow
+	for {
+		resp, err := client.ListMaintenanceWindowsWithContext(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, resp.MaintenanceWindows...)
+		if !resp.More || len(resp.MaintenanceWindows) == 0 {
+			break
+		}
+		opts.Offset += uint(len(resp.MaintenanceWindows))
+	}
+	return windows, nil
+}
+
+// ongoingMaintenance returns the ongoing maintenance windows of the teams,
+// indexed by the IDs of the services they cover.
+func ongoingMaintenance(ctx context.Context, client *pagerduty.Client, teamIDs []string) (map[string]pagerduty.MaintenanceWindow, error) {
+	windows, err := listMaintenanceWindows(ctx, client, pagerduty.ListMaintenanceWindowsOptions{TeamIDs: teamIDs, Filter: "ongoing"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ongoing maintenance windows: %w", err)
+	}
+	byService := make(map[string]pagerduty.MaintenanceWindow)
+	for _, mw := range windows {
+		for _, s := range mw.Services {
+			byService[s.ID] = mw
+		}
+	}
+	return byService, nil
+}
+
+// maintenanceWarning returns a warning to print next to a service that is
+// in maintenance.
+func maintenanceWarning(mw pagerduty.MaintenanceWindow, loc *time.Location) string {
+	end, err := time.Parse(time.RFC3339, mw.EndTime)
+	until := mw.EndTime
+	if err == nil {
+		until = end.In(loc).Format(shiftTimeFormat)
+	}
+	return color.YellowString("in maintenance until %s (%s, ID: %s)", until, mw.Description, mw.ID)
+}
+
+// resolveMaintenanceServices resolves the services to put in maintenance.
+// Each value is a service ID, the name or component of a shortlist entry, or
+// a query matching service names.
+func resolveMaintenanceServices(ctx context.Context, client *pagerduty.Client, cfg *config.Config, values []string) ([]pagerduty.APIObject, error) {
+	var teamIDs []string
+	if len(cfg.PagerDuty.Teams) > 0 {
+		var err error
+		teamIDs, err = resolveTeamIDs(ctx, client, cfg.PagerDuty.Teams)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var (
+		out  []pagerduty.APIObject
+		seen = make(map[string]struct{})
+	)
+	add := func(s pagerduty.APIObject) {
+		if _, ok := seen[s.ID]; !ok {
+			seen[s.ID] = struct{}{}
+			out = append(out, s)
+		}
+	}
+	for _, v := range values {
+		if entry := findShortlistEntry(cfg.Oncall.Shortlist, v); entry != nil && !pagerDutyIDRegexp.MatchString(v) {
+			services, err := shortlistServices(ctx, client, teamIDs, *entry)
+			if err != nil {
+				return nil, err
+			}
+			if len(services) == 0 {
+				return nil, fmt.Errorf("no services found for shortlist entry %q", entry.Name)
+			}
+			for _, s := range services {
+				add(serviceReference(s))
+			}
+			continue
+		}
+		s, err := lookupService(ctx, client, teamIDs, v)
+		if err != nil {
+			return nil, err
+		}
+		add(serviceReference(*s))
+	}
+	return out, nil
+}
+
+func serviceReference(s pagerduty.Service) pagerduty.APIObject {
+	return pagerduty.APIObject{ID: s.ID, Type: "service_reference", Summary: s.Name, HTMLURL: s.HTMLURL}
+}
+
+// findShortlistEntry returns the shortlist entry whose name or component is
+// s (case-insensitive), if any.
+func findShortlistEntry(entries []config.OncallShortlistEntry, s string) *config.OncallShortlistEntry {
+	for idx, e := range entries {
+		if strings.EqualFold(e.Name, s) || (e.Component != "" && strings.EqualFold(e.Component, s)) {
+			return &entries[idx]
+		}
+	}
+	return nil
+}
+
+// shortlistServices returns the services of the teams whose escalation
+// policy is the one of a shortlist entry, or includes any of its schedules.
+func shortlistServices(ctx context.Context, client *pagerduty.Client, teamIDs []string, e config.OncallShortlistEntry) ([]pagerduty.Service, error) {
+	scheduleIDs, err := shortlistScheduleIDs(ctx, client, e)
+	if err != nil {
+		return nil, err
+	}
+	policies := make(map[string]struct{})
+	if e.HasEscalationPolicy() {
+		ep, _, err := shortlistEscalationPolicy(ctx, client, e)
+		if err != nil {
+			return nil, err
+		}
+		policies[ep.ID] = struct{}{}
+	}
+	for _, scheduleID := range scheduleIDs {
+		sched, err := client.GetScheduleWithContext(ctx, scheduleID, pagerduty.GetScheduleOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get schedule with ID %q: %w", scheduleID, err)
+		}
+		for _, ep := range sched.EscalationPolicies {
+			policies[ep.ID] = struct{}{}
+		}
+	}
+	services, err := client.ListServicesPaginated(ctx, pagerduty.ListServiceOptions{TeamIDs: teamIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	var out []pagerduty.Service
+	for _, s := range services {
+		if _, ok := policies[s.EscalationPolicy.ID]; ok {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
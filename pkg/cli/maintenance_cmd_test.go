@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/config"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestFindShortlistEntry(t *testing.T) {
+	entries := []config.OncallShortlistEntry{
+		{Name: "Kubernetes", Component: "k8s", Query: "kubernetes"},
+		{Name: "Storage", Query: "storage"},
+	}
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{in: "kubernetes", want: "Kubernetes"},
+		{in: "K8S", want: "Kubernetes"},
+		{in: "storage", want: "Storage"},
+		{in: "stor", want: ""},
+		{in: "", want: ""},
+	} {
+		got := findShortlistEntry(entries, tc.in)
+		gotName := ""
+		if got != nil {
+			gotName = got.Name
+		}
+		if gotName != tc.want {
+			t.Errorf("findShortlistEntry(%q) = %q, want %q", tc.in, gotName, tc.want)
+		}
+	}
+}
+
+func TestMaintenanceStarted(t *testing.T) {
+	mw := pagerduty.MaintenanceWindow{APIObject: pagerduty.APIObject{ID: "PMW0001"}, StartTime: "2026-10-19T10:00:00Z", EndTime: "2026-10-19T12:00:00Z"}
+	for _, tc := range []struct {
+		now     time.Time
+		want    bool
+		wantErr bool
+	}{
+		{now: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), want: false},
+		{now: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC), want: true},
+		{now: time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC), want: true},
+		{now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), wantErr: true},
+	} {
+		got, err := maintenanceStarted(mw, tc.now)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("maintenanceStarted at %s: got error %v, want error %v", tc.now, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("maintenanceStarted at %s = %v, want %v", tc.now, got, tc.want)
+		}
+	}
+}
+
+func TestFormatMaintenanceWindow(t *testing.T) {
+	for _, tc := range []struct {
+		mw   pagerduty.MaintenanceWindow
+		want string
+	}{
+		{
+			mw:   pagerduty.MaintenanceWindow{StartTime: "2024-03-01T10:00:00Z", EndTime: "2024-03-01T12:00:00Z"},
+			want: "Fri 01 Mar 2024 10:00 UTC - Fri 01 Mar 2024 12:00 UTC",
+		},
+		{
+			mw:   pagerduty.MaintenanceWindow{StartTime: "bogus", EndTime: "2024-03-01T12:00:00Z"},
+			want: "bogus - 2024-03-01T12:00:00Z",
+		},
+	} {
+		if got := formatMaintenanceWindow(tc.mw, time.UTC); got != tc.want {
+			t.Errorf("formatMaintenanceWindow(%+v) = %q, want %q", tc.mw, got, tc.want)
+		}
+	}
+}
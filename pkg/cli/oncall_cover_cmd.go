@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/ansi"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagOncallCoverBy        string
+	flagOncallCoverFor       string
+	flagOncallCoverFrom      string
+	flagOncallCoverTo        string
+	flagOncallCoverSchedules []string
+	flagOncallCoverDryRun    bool
+	flagOncallCoverYes       bool
+)
+
+func init() {
+	OncallCmd.AddCommand(OncallCoverCmd)
+	OncallCoverCmd.Flags().StringVarP(&flagOncallCoverBy, "by", "b", "", "User covering the shifts")
+	OncallCoverCmd.Flags().StringVar(&flagOncallCoverFor, "for", "", "User whose shifts are covered (default: the owner of the PagerDuty token)")
+	OncallCoverCmd.Flags().StringVarP(&flagOncallCoverFrom, "from", "f", "", "Start of the range to cover. Either a date (YYYY-MM-DD, in the configured timezone) or any format accepted by the override command")
+	OncallCoverCmd.Flags().StringVarP(&flagOncallCoverTo, "to", "t", "", "End of the range to cover. A date (YYYY-MM-DD) includes that whole day")
+	OncallCoverCmd.Flags().StringSliceVarP(&flagOncallCoverSchedules, "schedule", "S", nil, "Schedule (ID or name) to cover, can be repeated (default: every schedule with shifts in the range)")
+	OncallCoverCmd.Flags().BoolVarP(&flagOncallCoverDryRun, "dry-run", "n", false, "Only print the overrides that would be created")
+	OncallCoverCmd.Flags().BoolVarP(&flagOncallCoverYes, "yes", "y", false, "Do not ask for confirmation before creating the overrides")
+}
+
+var OncallCoverCmd = &cobra.Command{
+	Use:   "cover --by <user> --from <date> --to <date>",
+	Short: "Have another user cover all your shifts in a date range (PagerDuty)",
+	Long: `Create overrides so that another user covers all your shifts in a date range.
+
+Every interval where you (or the user passed via --for) are oncall between
+--from and --to is looked up in the rendered final schedules, and a matching
+override is created for the user passed via --by. Without --schedule, every
+schedule where you have shifts in the range is considered.
+
+The plan is always printed first; use --dry-run to stop there.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logrus.Debugf("Running oncall cover command")
+		ctx := context.Background()
+		cfg, err := GetConfig()
+		if err != nil {
+			return err
+		}
+		if flagOncallCoverBy == "" {
+			return fmt.Errorf("covering user not specified, use --by")
+		}
+		if flagOncallCoverFrom == "" || flagOncallCoverTo == "" {
+			return fmt.Errorf("both --from and --to must be specified")
+		}
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return fmt.Errorf("cannot load timezone %q: %w", cfg.Timezone, err)
+		}
+		from, _, err := parseOverrideDateString(flagOncallCoverFrom, loc)
+		if err != nil {
+			return fmt.Errorf("failed to parse --from: %w", err)
+		}
+		to, dateOnly, err := parseOverrideDateString(flagOncallCoverTo, loc)
+		if err != nil {
+			return fmt.Errorf("failed to parse --to: %w", err)
+		}
+		if dateOnly {
+			// include the whole day
+			end := to.AddDate(0, 0, 1)
+			to = &end
+		}
+		if !to.After(*from) {
+			return fmt.Errorf("--to must be after --from")
+		}
+
+		client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+		var owner *pagerduty.User
+		if flagOncallCoverFor != "" {
+			owner, err = lookupUser(ctx, client, flagOncallCoverFor, true)
+		} else {
+			owner, err = client.GetCurrentUserWithContext(ctx, pagerduty.GetCurrentUserOptions{})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get the user whose shifts are covered: %w", err)
+		}
+		coverer, err := lookupUser(ctx, client, flagOncallCoverBy, true)
+		if err != nil {
+			return err
+		}
+		if coverer.ID == owner.ID {
+			return fmt.Errorf("%s <%s> cannot cover their own shifts", owner.Name, owner.Email)
+		}
+
+		var scheduleIDs []string
+		for _, s := range flagOncallCoverSchedules {
+			scheduleID, err := resolveScheduleID(ctx, client, s)
+			if err != nil {
+				return err
+			}
+			scheduleIDs = append(scheduleIDs, scheduleID)
+		}
+		if len(scheduleIDs) == 0 {
+			oncalls, err := listOnCalls(ctx, client, pagerduty.ListOnCallOptions{
+				UserIDs: []string{owner.ID},
+				Since:   from.Format(time.RFC3339),
+				Until:   to.Format(time.RFC3339),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to get oncalls for %s: %w", owner.Name, err)
+			}
+			seen := make(map[string]struct{})
+			for _, oc := range oncalls {
+				// oncalls from escalation policies targeting the user
+				// directly have no schedule and can't be overridden
+				if oc.Schedule.ID == "" {
+					continue
+				}
+				if _, ok := seen[oc.Schedule.ID]; ok {
+					continue
+				}
+				seen[oc.Schedule.ID] = struct{}{}
+				scheduleIDs = append(scheduleIDs, oc.Schedule.ID)
+			}
+		}
+
+		type coverPlan struct {
+			schedule *pagerduty.Schedule
+			shifts   []scheduleSegment
+		}
+		var (
+			plans  []coverPlan
+			shifts int
+		)
+		fmt.Printf("Shifts of %s <%s> from %s to %s, to be covered by %s <%s>\n",
+			owner.Name, owner.Email, from.Format(shiftTimeFormat), to.Format(shiftTimeFormat), coverer.Name, coverer.Email)
+		for _, scheduleID := range scheduleIDs {
+			sched, segments, err := fetchSchedule(ctx, client, scheduleID, *from, *to, cfg.Timezone)
+			if err != nil {
+				return err
+			}
+			own := userSegments(segments, owner.ID, *from, *to)
+			fmt.Printf("%s\n", ansi.Bold(ansi.ToURL(sched.Name, sched.HTMLURL)))
+			if len(own) == 0 {
+				fmt.Printf("    no shifts in range\n")
+				continue
+			}
+			for _, s := range own {
+				fmt.Printf("    %s\n", s)
+			}
+			plans = append(plans, coverPlan{schedule: sched, shifts: own})
+			shifts += len(own)
+		}
+		if shifts == 0 {
+			fmt.Printf("Nothing to cover\n")
+			return nil
+		}
+		if flagOncallCoverDryRun {
+			fmt.Printf("Dry run, %d overrides not created\n", shifts)
+			return nil
+		}
+		if !flagOncallCoverYes {
+			ok, err := confirm(fmt.Sprintf("Do you want to create %d overrides for %s <%s>?", shifts, coverer.Name, coverer.Email))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Printf("\nAborting\n")
+				return nil
+			}
+		}
+
+		var failed int
+		for _, p := range plans {
+			for _, s := range p.shifts {
+				override := pagerduty.Override{
+					User:  coverer.APIObject,
+					Start: s.Start.Format(time.RFC3339),
+					End:   s.End.Format(time.RFC3339),
+				}
+				if _, err := client.CreateOverrideWithContext(ctx, p.schedule.ID, override); err != nil {
+					logrus.Warningf("Failed to create override on %q for %s: %v", p.schedule.Name, s, err)
+					failed++
+					continue
+				}
+				fmt.Printf("Override created on %s for %s\n", p.schedule.Name, s)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d overrides could not be created", failed, shifts)
+		}
+		fmt.Printf("All %d overrides created\n", shifts)
+		return nil
+	},
+}
+
+// userSegments returns the segments where userID is oncall, clipped to the
+// [since, until) range.
+func userSegments(segments []scheduleSegment, userID string, since, until time.Time) []scheduleSegment {
+	var out []scheduleSegment
+	for _, s := range segments {
+		if s.User.ID != userID || !s.End.After(since) || !s.Start.Before(until) {
+			continue
+		}
+		if s.Start.Before(since) {
+			s.Start = since
+		}
+		if s.End.After(until) {
+			s.End = until
+		}
+		out = append(out, s)
+	}
+	return out
+}
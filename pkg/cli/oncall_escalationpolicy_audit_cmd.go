@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/ansi"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	str2duration "github.com/xhit/go-str2duration/v2"
+)
+
+var (
+	flagOncallEscalationPolicyAuditHorizon string
+	flagOncallEscalationPolicyAuditStrict  bool
+)
+
+func init() {
+	OncallEscalationPolicyCmd.AddCommand(OncallEscalationPolicyAuditCmd)
+	OncallEscalationPolicyAuditCmd.Flags().StringVarP(&flagOncallEscalationPolicyAuditHorizon, "horizon", "d", "14d", "How far in the future to look for gaps in the schedules")
+	OncallEscalationPolicyAuditCmd.Flags().BoolVar(&flagOncallEscalationPolicyAuditStrict, "strict", false, "Exit with an error on warnings too, not only on errors")
+}
+
+var OncallEscalationPolicyAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check the escalation policies of the configured teams for common mistakes (PagerDuty)",
+	Long: `Check every escalation policy of the teams in ` + "`pagerduty.teams`" + ` and report:
+
+  * levels whose schedules have gaps within --horizon (error)
+  * users targeted by a level that cannot be paged: deleted, with a
+    stakeholder (read-only) role, or without contact methods (error)
+  * levels targeting a single user rather than a schedule (warning)
+  * policies with a single level that do not repeat (warning)
+  * services of the teams using a policy of another team (warning)
+  * policies not attached to any service (info)
+
+The command exits with an error if any error is found, or any warning with
+--strict, so it can be used in CI.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logrus.Debugf("Running oncall escalationpolicy audit command")
+		ctx := context.Background()
+		cfg, err := GetConfig()
+		if err != nil {
+			return err
+		}
+		horizon, err := str2duration.ParseDuration(flagOncallEscalationPolicyAuditHorizon)
+		if err != nil {
+			return fmt.Errorf("failed to parse --horizon %q: %w", flagOncallEscalationPolicyAuditHorizon, err)
+		}
+		client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+		teamIDs, err := resolveTeamIDs(ctx, client, cfg.PagerDuty.Teams)
+		if err != nil {
+			return err
+		}
+
+		data := escalationPolicyAuditData{
+			ScheduleGaps:     make(map[string][]timeRange),
+			UnreachableUsers: make(map[string]string),
+		}
+		data.Policies, err = listEscalationPolicies(ctx, client, pagerduty.ListEscalationPoliciesOptions{
+			TeamIDs:  teamIDs,
+			Includes: []string{"services", "targets", "teams"},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get escalation policies: %w", err)
+		}
+		data.Services, err = client.ListServicesPaginated(ctx, pagerduty.ListServiceOptions{TeamIDs: teamIDs})
+		if err != nil {
+			return fmt.Errorf("failed to get services: %w", err)
+		}
+		now := time.Now()
+		data.Since, data.Until = now, now.Add(horizon)
+		checkedUsers := make(map[string]struct{})
+		for _, ep := range data.Policies {
+			for _, r := range ep.EscalationRules {
+				for _, t := range r.Targets {
+					target := escalationTarget{Target: t}
+					if target.isScheduleTarget() {
+						if _, ok := data.ScheduleGaps[t.ID]; ok {
+							continue
+						}
+						_, segments, err := fetchSchedule(ctx, client, t.ID, data.Since, data.Until, cfg.Timezone)
+						if err != nil {
+							return err
+						}
+						data.ScheduleGaps[t.ID] = scheduleGaps(segments, data.Since, data.Until)
+						continue
+					}
+					if _, ok := checkedUsers[t.ID]; ok {
+						continue
+					}
+					checkedUsers[t.ID] = struct{}{}
+					u, err := client.GetUserWithContext(ctx, t.ID, pagerduty.GetUserOptions{Includes: []string{"contact_methods"}})
+					if err != nil {
+						var aerr pagerduty.APIError
+						if !errors.As(err, &aerr) || !aerr.NotFound() {
+							return fmt.Errorf("failed to get user %q: %w", t.Summary, err)
+						}
+						data.UnreachableUsers[t.ID] = "no longer exists"
+						continue
+					}
+					if reason := userUnpageableReason(u); reason != "" {
+						data.UnreachableUsers[t.ID] = reason
+					}
+				}
+			}
+		}
+
+		fmt.Printf("Audited %d escalation policies and %d services\n\n", len(data.Policies), len(data.Services))
+		failOn := auditError
+		if flagOncallEscalationPolicyAuditStrict {
+			failOn = auditWarning
+		}
+		if err := printAuditFindings(auditEscalationPolicies(&data), failOn); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		return nil
+	},
+}
+
+// escalationPolicyAuditData is what the escalation policy audit looks at:
+// the policies and services of the configured teams, the gaps of the
+// schedules targeted by the policies between Since and Until, and why the
+// targeted users that cannot be paged can't, by user ID.
+type escalationPolicyAuditData struct {
+	Policies         []pagerduty.EscalationPolicy
+	Services         []pagerduty.Service
+	Since            time.Time
+	Until            time.Time
+	ScheduleGaps     map[string][]timeRange
+	UnreachableUsers map[string]string
+}
+
+// stakeholderRoles are the account roles of stakeholder users, who cannot be
+// oncall.
+var stakeholderRoles = map[string]struct{}{
+	"read_only_user":         {},
+	"read_only_limited_user": {},
+}
+
+// userUnpageableReason returns why a user targeted by an escalation policy
+// cannot be paged, or an empty string if they can. The user must include its
+// contact methods.
+func userUnpageableReason(u *pagerduty.User) string {
+	if _, ok := stakeholderRoles[u.Role]; ok {
+		return fmt.Sprintf("has the stakeholder role %s and cannot be oncall", u.Role)
+	}
+	if len(u.ContactMethods) == 0 {
+		return "has no contact methods"
+	}
+	return ""
+}
+
+// auditEscalationPolicies returns the findings of the escalation policy audit.
+func auditEscalationPolicies(d *escalationPolicyAuditData) []auditFinding {
+	var findings []auditFinding
+	own := make(map[string]struct{}, len(d.Policies))
+	for _, ep := range d.Policies {
+		own[ep.ID] = struct{}{}
+		subject := ansi.ToURL(ep.Name, ep.HTMLURL)
+		add := func(sev auditSeverity, format string, a ...interface{}) {
+			findings = append(findings, auditFinding{Severity: sev, Subject: subject, Message: fmt.Sprintf(format, a...)})
+		}
+		if len(ep.Services) == 0 {
+			add(auditInfo, "not attached to any service")
+		}
+		if ep.NumLoops == 0 && len(ep.EscalationRules) == 1 {
+			add(auditWarning, "has a single level and does not repeat, nobody else is notified if the incident is not acknowledged")
+		}
+		for idx, r := range ep.EscalationRules {
+			level := idx + 1
+			if len(r.Targets) == 1 && !(escalationTarget{Target: r.Targets[0]}).isScheduleTarget() {
+				add(auditWarning, "level %d targets only the user %s", level, r.Targets[0].Summary)
+			}
+			for _, t := range r.Targets {
+				if (escalationTarget{Target: t}).isScheduleTarget() {
+					gaps := d.ScheduleGaps[t.ID]
+					if len(gaps) == 0 {
+						continue
+					}
+					msg := fmt.Sprintf("level %d schedule %s has nobody oncall from %s to %s", level, t.Summary, gaps[0].Start.Format(shiftTimeFormat), gaps[0].End.Format(shiftTimeFormat))
+					if len(gaps) > 1 {
+						msg += fmt.Sprintf(", plus %d more gaps before %s", len(gaps)-1, d.Until.Format(shiftTimeFormat))
+					}
+					add(auditError, "%s", msg)
+				} else if reason, ok := d.UnreachableUsers[t.ID]; ok {
+					add(auditError, "level %d targets the user %s, who %s", level, t.Summary, reason)
+				}
+			}
+		}
+	}
+	for _, s := range d.Services {
+		if _, ok := own[s.EscalationPolicy.ID]; ok {
+			continue
+		}
+		findings = append(findings, auditFinding{
+			Severity: auditWarning,
+			Subject:  ansi.ToURL(s.Name, s.HTMLURL),
+			Message:  fmt.Sprintf("uses the escalation policy %s, which does not belong to the configured teams", s.EscalationPolicy.Summary),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestAuditEscalationPolicies(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	schedule := pagerduty.APIObject{ID: "PSCHED1", Type: "schedule_reference", Summary: "Primary"}
+	bob := pagerduty.APIObject{ID: "PUSER02", Type: "user_reference", Summary: "Bob"}
+	gone := pagerduty.APIObject{ID: "PUSER09", Type: "user_reference", Summary: "Eve"}
+	data := escalationPolicyAuditData{
+		Policies: []pagerduty.EscalationPolicy{
+			{
+				APIObject: pagerduty.APIObject{ID: "PEP0001"},
+				Name:      "healthy",
+				NumLoops:  1,
+				Services:  []pagerduty.APIObject{{ID: "PSVC001"}},
+				EscalationRules: []pagerduty.EscalationRule{
+					{Delay: 30, Targets: []pagerduty.APIObject{schedule}},
+					{Delay: 30, Targets: []pagerduty.APIObject{schedule, bob}},
+				},
+			},
+			{
+				APIObject: pagerduty.APIObject{ID: "PEP0002"},
+				Name:      "fragile",
+				EscalationRules: []pagerduty.EscalationRule{
+					{Delay: 30, Targets: []pagerduty.APIObject{gone}},
+				},
+			},
+		},
+		Services: []pagerduty.Service{
+			{Name: "mine", EscalationPolicy: pagerduty.EscalationPolicy{APIObject: pagerduty.APIObject{ID: "PEP0001"}}},
+			{Name: "borrowed", EscalationPolicy: pagerduty.EscalationPolicy{APIObject: pagerduty.APIObject{ID: "PEP0009", Summary: "Other team"}}},
+		},
+		Since:            now,
+		Until:            now.Add(24 * time.Hour),
+		ScheduleGaps:     map[string][]timeRange{"PSCHED1": nil},
+		UnreachableUsers: map[string]string{"PUSER09": "no longer exists"},
+	}
+	var got []string
+	for _, f := range auditEscalationPolicies(&data) {
+		got = append(got, f.Severity.String()+" "+f.Message)
+	}
+	sort.Strings(got)
+	want := []string{
+		"ERROR level 1 targets the user Eve, who no longer exists",
+		"INFO not attached to any service",
+		"WARNING has a single level and does not repeat, nobody else is notified if the incident is not acknowledged",
+		"WARNING level 1 targets only the user Eve",
+		"WARNING uses the escalation policy Other team, which does not belong to the configured teams",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got findings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	data.ScheduleGaps["PSCHED1"] = []timeRange{{Start: now, End: now.Add(time.Hour)}, {Start: now.Add(5 * time.Hour), End: now.Add(6 * time.Hour)}}
+	gaps := 0
+	for _, f := range auditEscalationPolicies(&data) {
+		if f.Severity == auditError && strings.Contains(f.Message, "schedule Primary has nobody oncall") {
+			gaps++
+			if !strings.Contains(f.Message, "plus 1 more gaps") {
+				t.Errorf("got %q, want the number of further gaps", f.Message)
+			}
+		}
+	}
+	if gaps != 2 {
+		t.Fatalf("got %d gap findings, want one per level targeting the schedule", gaps)
+	}
+}
+
+func TestUserUnpageableReason(t *testing.T) {
+	phone := pagerduty.ContactMethod{Type: "phone_contact_method"}
+	for _, tc := range []struct {
+		name string
+		user pagerduty.User
+		want string
+	}{
+		{name: "pageable", user: pagerduty.User{Role: "user", ContactMethods: []pagerduty.ContactMethod{phone}}},
+		{name: "stakeholder", user: pagerduty.User{Role: "read_only_user", ContactMethods: []pagerduty.ContactMethod{phone}}, want: "has the stakeholder role read_only_user and cannot be oncall"},
+		{name: "no contact methods", user: pagerduty.User{Role: "limited_user"}, want: "has no contact methods"},
+	} {
+		if got := userUnpageableReason(&tc.user); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
 
@@ -12,15 +14,31 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	flagOncallEscalationPolicyAll    bool
+	flagOncallEscalationPolicyFormat string
+)
+
 func init() {
 	OncallCmd.AddCommand(OncallEscalationPolicyCmd)
+	OncallEscalationPolicyCmd.Flags().BoolVarP(&flagOncallEscalationPolicyAll, "all", "a", false, "Show all the escalation policies matching the query instead of picking one")
+	OncallEscalationPolicyCmd.Flags().StringVarP(&flagOncallEscalationPolicyFormat, "format", "F", "text", "Output format: text, tree, dot (Graphviz) or mermaid")
 }
 
 var OncallEscalationPolicyCmd = &cobra.Command{
 	Use:     "escalationpolicy",
 	Aliases: []string{"ep", "escalation-policy", "escalation_policy"},
 	Short:   "Show escalation policy information",
-	Args:    cobra.MinimumNArgs(1),
+	Long: `Show escalation policy information.
+
+With --format tree, dot or mermaid the structure of the policy is rendered
+instead: the services using it, its levels with their escalation delay and
+repeat count, the schedules and users they target, and who is currently
+oncall for them. The dot and mermaid outputs can be piped to Graphviz or
+pasted in a Markdown document, e.g.:
+
+  sre oncall escalationpolicy --format dot payments | dot -Tsvg > payments.svg`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		logrus.Debugf("Running escalationpolicy command")
 		ctx := context.Background()
@@ -29,6 +47,19 @@ var OncallEscalationPolicyCmd = &cobra.Command{
 			return err
 		}
 
+		var render func(io.Writer, []*escalationPolicyGraph)
+		switch flagOncallEscalationPolicyFormat {
+		case "text":
+		case "tree":
+			render = renderEscalationPolicyTree
+		case "dot":
+			render = renderEscalationPolicyDOT
+		case "mermaid":
+			render = renderEscalationPolicyMermaid
+		default:
+			return fmt.Errorf("unknown format %q, must be one of text, tree, dot, mermaid", flagOncallEscalationPolicyFormat)
+		}
+
 		// search for an escalation policy
 		client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
 		query := strings.Join(args, " ")
@@ -41,10 +72,35 @@ var OncallEscalationPolicyCmd = &cobra.Command{
 		if err != nil {
 			logrus.Fatalf("Failed to get escalation policies: %v", err)
 		}
-		// cache teams to minimize API calls
+		policies := resp.EscalationPolicies
+		if len(policies) > 1 && !flagOncallEscalationPolicyAll {
+			items := make([]string, 0, len(policies))
+			for _, ep := range policies {
+				items = append(items, ep.Name)
+			}
+			idx, err := pick(fmt.Sprintf("escalation policies matching %q", query), items)
+			if err != nil {
+				return err
+			}
+			policies = policies[idx : idx+1]
+		}
+		if render != nil {
+			now := time.Now()
+			graphs := make([]*escalationPolicyGraph, 0, len(policies))
+			for _, ep := range policies {
+				g, err := buildEscalationPolicyGraph(ctx, client, ep, now)
+				if err != nil {
+					return err
+				}
+				graphs = append(graphs, g)
+			}
+			render(os.Stdout, graphs)
+			return nil
+		}
+		// cache teams and users to minimize API calls
 		teams := make(map[string]*pagerduty.Team)
 		users := make(map[string]*pagerduty.User)
-		for _, ep := range resp.EscalationPolicies {
+		for _, ep := range policies {
 			fmt.Printf(ansi.Bold("Name:")+" %s\n", ansi.ToURL(ep.Name, ep.HTMLURL))
 			fmt.Printf(ansi.Bold("Description:")+" %s\n", ep.Description)
 			fmt.Print(ansi.Bold("Services:") + "\n")
@@ -69,58 +125,29 @@ var OncallEscalationPolicyCmd = &cobra.Command{
 				fmt.Printf("    %s (Description: %q)\n", ansi.ToURL(team.Summary, team.HTMLURL), team.Description)
 			}
 			fmt.Print(ansi.Bold("Escalation rules:") + "\n")
-			for _, r := range ep.EscalationRules {
-				for _, t := range r.Targets {
-					fmt.Printf("    %s\n", ansi.ToURL(t.Summary, t.HTMLURL))
-					now := time.Now()
-					pastHour := now.Add(-time.Hour)
-					nextHour := now.Add(time.Hour)
-					oopts := pagerduty.ListOnCallUsersOptions{
-						Since: pastHour.Format(time.RFC3339),
-						Until: now.Format(time.RFC3339),
-					}
-					// FIXME this approach is not great. I get the
-					// oncalls for the past hour, and for the next hour,
-					// and print them without repetitions. Need to find
-					// a way to get subsequent oncalls from the
-					// PagerDuty API instead.
-					if t.Type == "user" {
-						user, ok := users[t.ID]
+			g, err := buildEscalationPolicyGraph(ctx, client, ep, time.Now())
+			if err != nil {
+				return err
+			}
+			for _, l := range g.Levels {
+				for _, t := range l.Targets {
+					fmt.Printf("    %s\n", ansi.ToURL(t.Target.Summary, t.Target.HTMLURL))
+					if !t.isScheduleTarget() {
+						// users targeted directly have no oncall entry
+						// with their details, fetch them
+						user, ok := users[t.Target.ID]
 						if !ok {
-							// fetch the user
-							opts := pagerduty.GetUserOptions{
-								Includes: []string{"contact_methods"},
-							}
-							user, err = client.GetUserWithContext(ctx, t.ID, opts)
+							user, err = client.GetUserWithContext(ctx, t.Target.ID, pagerduty.GetUserOptions{})
 							if err != nil {
-								logrus.Fatalf("Failed to get user: %v", err)
+								return fmt.Errorf("failed to get user %q: %w", t.Target.Summary, err)
 							}
 							users[user.ID] = user
 						}
-						fmt.Printf("        %s (%s)\n", ansi.ToURL(user.Name, user.HTMLURL), ansi.ToURL(user.Email, "mailto:"+user.Email))
-					} else {
-						currentOncalls, err := client.ListOnCallUsersWithContext(ctx, t.ID, oopts)
-						if err != nil {
-							logrus.Warningf("Failed to get users for schedule %s, skipping. Error was: %v", t.Summary, err)
-						}
-						oopts.Since = now.Format(time.RFC3339)
-						oopts.Until = nextHour.Format(time.RFC3339)
-						nextOncalls, err := client.ListOnCallUsersWithContext(ctx, t.ID, oopts)
-						if err != nil {
-							logrus.Warningf("Failed to get users for schedule %s (ID: %s), skipping. Error was: %v", t.Summary, t.ID, err)
-						}
-						alreadyPrinted := make(map[string]struct{})
-						for _, u := range currentOncalls {
-							fmt.Printf("        %s (%s)\n", ansi.ToURL(u.Name, u.HTMLURL), ansi.ToURL(u.Email, "mailto:"+u.Email))
-							alreadyPrinted[u.ID] = struct{}{}
-						}
-						for _, u := range nextOncalls {
-							if _, ok := alreadyPrinted[u.ID]; ok {
-								// already printed, don't print it again
-								continue
-							}
-							fmt.Printf("        %s (%s)\n", ansi.ToURL(u.Name, u.HTMLURL), ansi.ToURL(u.Email, "mailto:"+u.Email))
-						}
+						fmt.Printf("        %s\n", formatOncallUser(*user))
+						continue
+					}
+					for _, u := range t.Oncall {
+						fmt.Printf("        %s\n", formatOncallUser(u))
 					}
 				}
 			}
@@ -129,3 +156,16 @@ var OncallEscalationPolicyCmd = &cobra.Command{
 		return nil
 	},
 }
+
+// formatOncallUser formats a user as a link to their profile, followed by
+// their e-mail address if known.
+func formatOncallUser(u pagerduty.User) string {
+	name := u.Name
+	if name == "" {
+		name = u.Summary
+	}
+	if u.Email == "" {
+		return ansi.ToURL(name, u.HTMLURL)
+	}
+	return fmt.Sprintf("%s (%s)", ansi.ToURL(name, u.HTMLURL), ansi.ToURL(u.Email, "mailto:"+u.Email))
+}
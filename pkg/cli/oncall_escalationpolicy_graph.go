@@ -0,0 +1,307 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+// escalationPolicyGraph is the structure of an escalation policy: the
+// services routing to it, and its levels with their targets and the people
+// currently oncall for them.
+type escalationPolicyGraph struct {
+	Policy   pagerduty.EscalationPolicy
+	Services []pagerduty.APIObject
+	Levels   []escalationLevel
+}
+
+// escalationLevel is an escalation rule of a policy. Number is 1-based, like
+// PagerDuty's escalation levels.
+type escalationLevel struct {
+	Number  int
+	Delay   uint
+	Targets []escalationTarget
+}
+
+// escalationTarget is a schedule or user targeted by an escalation level,
+// with the users that are oncall for it.
+type escalationTarget struct {
+	Target pagerduty.APIObject
+	Oncall []pagerduty.User
+}
+
+// isScheduleTarget reports whether the target is a schedule rather than a
+// user.
+func (t escalationTarget) isScheduleTarget() bool {
+	return strings.HasPrefix(t.Target.Type, "schedule")
+}
+
+// buildEscalationPolicyGraph builds the graph of ep, resolving who is oncall
+// for each target at the given time.
+func buildEscalationPolicyGraph(ctx context.Context, client *pagerduty.Client, ep pagerduty.EscalationPolicy, at time.Time) (*escalationPolicyGraph, error) {
+	oncalls, err := listOnCalls(ctx, client, pagerduty.ListOnCallOptions{
+		EscalationPolicyIDs: []string{ep.ID},
+		Includes:            []string{"users"},
+		Since:               at.Format(time.RFC3339),
+		Until:               at.Add(time.Minute).Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oncalls for escalation policy %q: %w", ep.Name, err)
+	}
+	return newEscalationPolicyGraph(ep, oncalls, at), nil
+}
+
+// newEscalationPolicyGraph builds the graph of ep from the oncall entries of
+// the policy, as they are at the given time.
+func newEscalationPolicyGraph(ep pagerduty.EscalationPolicy, oncalls []pagerduty.OnCall, at time.Time) *escalationPolicyGraph {
+	g := escalationPolicyGraph{Policy: ep, Services: ep.Services}
+	for idx, r := range ep.EscalationRules {
+		g.Levels = append(g.Levels, escalationLevel{
+			Number:  idx + 1,
+			Delay:   r.Delay,
+			Targets: levelTargets(r.Targets, idx+1, oncalls, at),
+		})
+	}
+	return &g
+}
+
+// levelTargets resolves who is oncall at the given time for each of the
+// targets of an escalation level. Users targeted directly are always oncall.
+func levelTargets(targets []pagerduty.APIObject, level int, oncalls []pagerduty.OnCall, at time.Time) []escalationTarget {
+	out := make([]escalationTarget, 0, len(targets))
+	for _, t := range targets {
+		target := escalationTarget{Target: t}
+		seen := make(map[string]struct{})
+		for _, oc := range oncalls {
+			if oc.EscalationLevel != uint(level) || !onCallActiveAt(oc, at) {
+				continue
+			}
+			if target.isScheduleTarget() {
+				if oc.Schedule.ID != t.ID {
+					continue
+				}
+			} else if oc.Schedule.ID != "" || oc.User.ID != t.ID {
+				continue
+			}
+			if _, ok := seen[oc.User.ID]; ok {
+				continue
+			}
+			seen[oc.User.ID] = struct{}{}
+			target.Oncall = append(target.Oncall, oc.User)
+		}
+		if !target.isScheduleTarget() && len(target.Oncall) == 0 {
+			target.Oncall = []pagerduty.User{{APIObject: t, Name: t.Summary}}
+		}
+		out = append(out, target)
+	}
+	return out
+}
+
+// onCallActiveAt reports whether the oncall entry covers the given time. An
+// empty start or end means the entry is not bounded on that side, which is
+// the case for users targeted directly by an escalation policy.
+func onCallActiveAt(oc pagerduty.OnCall, at time.Time) bool {
+	if oc.Start != "" {
+		start, err := time.Parse(time.RFC3339, oc.Start)
+		if err == nil && at.Before(start) {
+			return false
+		}
+	}
+	if oc.End != "" {
+		end, err := time.Parse(time.RFC3339, oc.End)
+		if err == nil && !at.Before(end) {
+			return false
+		}
+	}
+	return true
+}
+
+// repeatDescription describes how many times the policy loops.
+func (g *escalationPolicyGraph) repeatDescription() string {
+	switch g.Policy.NumLoops {
+	case 0:
+		return "does not repeat"
+	case 1:
+		return "repeats once"
+	default:
+		return fmt.Sprintf("repeats %d times", g.Policy.NumLoops)
+	}
+}
+
+// delayDescription describes after how long a level escalates to the next.
+func (l escalationLevel) delayDescription() string {
+	return fmt.Sprintf("escalates after %d min", l.Delay)
+}
+
+func (t escalationTarget) description() string {
+	kind := "user"
+	if t.isScheduleTarget() {
+		kind = "schedule"
+	}
+	return fmt.Sprintf("%s: %s", kind, t.Target.Summary)
+}
+
+// renderEscalationPolicyTree renders the policies as an indented tree.
+func renderEscalationPolicyTree(w io.Writer, graphs []*escalationPolicyGraph) {
+	for _, g := range graphs {
+		fmt.Fprintf(w, "%s (%s)\n", g.Policy.Name, g.repeatDescription())
+		fmt.Fprintf(w, "├── services\n")
+		if len(g.Services) == 0 {
+			fmt.Fprintf(w, "│   └── (none)\n")
+		}
+		for idx, s := range g.Services {
+			fmt.Fprintf(w, "│   %s %s\n", treeBranch(idx, len(g.Services)), s.Summary)
+		}
+		fmt.Fprintf(w, "└── levels\n")
+		for lidx, l := range g.Levels {
+			lastLevel := lidx == len(g.Levels)-1
+			fmt.Fprintf(w, "    %s level %d (%s)\n", treeBranch(lidx, len(g.Levels)), l.Number, l.delayDescription())
+			levelIndent := "    " + treeIndent(lastLevel)
+			for tidx, t := range l.Targets {
+				lastTarget := tidx == len(l.Targets)-1
+				fmt.Fprintf(w, "%s%s %s\n", levelIndent, treeBranch(tidx, len(l.Targets)), t.description())
+				if !t.isScheduleTarget() {
+					continue
+				}
+				targetIndent := levelIndent + treeIndent(lastTarget)
+				if len(t.Oncall) == 0 {
+					fmt.Fprintf(w, "%s└── (nobody oncall)\n", targetIndent)
+				}
+				for uidx, u := range t.Oncall {
+					fmt.Fprintf(w, "%s%s oncall: %s\n", targetIndent, treeBranch(uidx, len(t.Oncall)), u.Summary)
+				}
+			}
+		}
+	}
+}
+
+func treeBranch(idx, count int) string {
+	if idx == count-1 {
+		return "└──"
+	}
+	return "├──"
+}
+
+func treeIndent(last bool) string {
+	if last {
+		return "    "
+	}
+	return "│   "
+}
+
+var graphIDSanitizer = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// graphNodeID returns a node identifier usable in both DOT and Mermaid.
+func graphNodeID(parts ...string) string {
+	return graphIDSanitizer.ReplaceAllString(strings.Join(parts, "_"), "_")
+}
+
+// renderEscalationPolicyDOT renders the policies as a Graphviz DOT graph.
+func renderEscalationPolicyDOT(w io.Writer, graphs []*escalationPolicyGraph) {
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+	}
+	fmt.Fprintf(w, "digraph escalation_policies {\n")
+	fmt.Fprintf(w, "  rankdir=LR;\n")
+	nodes := make(map[string]struct{})
+	node := func(id, label, shape string) {
+		if _, ok := nodes[id]; ok {
+			return
+		}
+		nodes[id] = struct{}{}
+		fmt.Fprintf(w, "  %s [label=%s, shape=%s];\n", id, quote(label), shape)
+	}
+	for _, g := range graphs {
+		epID := graphNodeID("ep", g.Policy.ID)
+		node(epID, g.Policy.Name+"\n"+g.repeatDescription(), "octagon")
+		for _, s := range g.Services {
+			sID := graphNodeID("service", s.ID)
+			node(sID, s.Summary, "box")
+			fmt.Fprintf(w, "  %s -> %s;\n", sID, epID)
+		}
+		prev := epID
+		for _, l := range g.Levels {
+			lID := graphNodeID("ep", g.Policy.ID, "level", fmt.Sprint(l.Number))
+			node(lID, fmt.Sprintf("level %d\n%s", l.Number, l.delayDescription()), "rectangle")
+			if prev == epID {
+				fmt.Fprintf(w, "  %s -> %s;\n", prev, lID)
+			} else {
+				fmt.Fprintf(w, "  %s -> %s [style=dashed, label=\"escalate\"];\n", prev, lID)
+			}
+			prev = lID
+			for _, t := range l.Targets {
+				if !t.isScheduleTarget() {
+					uID := graphNodeID("user", t.Target.ID)
+					node(uID, t.Target.Summary, "ellipse")
+					fmt.Fprintf(w, "  %s -> %s;\n", lID, uID)
+					continue
+				}
+				tID := graphNodeID("schedule", t.Target.ID)
+				node(tID, t.Target.Summary, "folder")
+				fmt.Fprintf(w, "  %s -> %s;\n", lID, tID)
+				for _, u := range t.Oncall {
+					uID := graphNodeID("user", u.ID)
+					node(uID, u.Summary, "ellipse")
+					fmt.Fprintf(w, "  %s -> %s [label=\"oncall\"];\n", tID, uID)
+				}
+			}
+		}
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
+// renderEscalationPolicyMermaid renders the policies as a Mermaid flowchart.
+func renderEscalationPolicyMermaid(w io.Writer, graphs []*escalationPolicyGraph) {
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`"`, "#quot;", "\n", "<br/>").Replace(s) + `"`
+	}
+	fmt.Fprintf(w, "flowchart LR\n")
+	nodes := make(map[string]struct{})
+	// node returns the node reference, with its label and shape the first
+	// time it is used
+	node := func(id, label, open, close string) string {
+		if _, ok := nodes[id]; ok {
+			return id
+		}
+		nodes[id] = struct{}{}
+		return id + open + quote(label) + close
+	}
+	for _, g := range graphs {
+		epID := graphNodeID("ep", g.Policy.ID)
+		ep := node(epID, g.Policy.Name+"\n"+g.repeatDescription(), "{{", "}}")
+		if len(g.Services) == 0 {
+			fmt.Fprintf(w, "  %s\n", ep)
+		}
+		for _, s := range g.Services {
+			fmt.Fprintf(w, "  %s --> %s\n", node(graphNodeID("service", s.ID), s.Summary, "[", "]"), ep)
+			ep = epID
+		}
+		prev := epID
+		for _, l := range g.Levels {
+			lID := graphNodeID("ep", g.Policy.ID, "level", fmt.Sprint(l.Number))
+			level := node(lID, fmt.Sprintf("level %d\n%s", l.Number, l.delayDescription()), "[", "]")
+			if prev == epID {
+				fmt.Fprintf(w, "  %s --> %s\n", prev, level)
+			} else {
+				fmt.Fprintf(w, "  %s -. escalate .-> %s\n", prev, level)
+			}
+			prev = lID
+			for _, t := range l.Targets {
+				if !t.isScheduleTarget() {
+					fmt.Fprintf(w, "  %s --> %s\n", lID, node(graphNodeID("user", t.Target.ID), t.Target.Summary, "(", ")"))
+					continue
+				}
+				tID := graphNodeID("schedule", t.Target.ID)
+				fmt.Fprintf(w, "  %s --> %s\n", lID, node(tID, t.Target.Summary, "[/", "/]"))
+				for _, u := range t.Oncall {
+					fmt.Fprintf(w, "  %s -- oncall --> %s\n", tID, node(graphNodeID("user", u.ID), u.Summary, "(", ")"))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func testEscalationPolicyGraph() *escalationPolicyGraph {
+	ep := pagerduty.EscalationPolicy{
+		APIObject: pagerduty.APIObject{ID: "PEP0001"},
+		Name:      "Payments",
+		NumLoops:  2,
+		Services:  []pagerduty.APIObject{{ID: "PSVC001", Summary: "payments-api"}},
+		EscalationRules: []pagerduty.EscalationRule{
+			{Delay: 30, Targets: []pagerduty.APIObject{{ID: "PSCHED1", Type: "schedule_reference", Summary: "Primary"}}},
+			{Delay: 15, Targets: []pagerduty.APIObject{{ID: "PUSER02", Type: "user_reference", Summary: "Bob"}}},
+		},
+	}
+	oncalls := []pagerduty.OnCall{
+		{EscalationLevel: 1, Schedule: pagerduty.Schedule{APIObject: pagerduty.APIObject{ID: "PSCHED1"}}, User: pagerduty.User{APIObject: pagerduty.APIObject{ID: "PUSER01", Summary: "Alice"}}},
+		// next shift, not oncall yet
+		{EscalationLevel: 1, Start: "2026-10-19T18:00:00Z", End: "2026-10-20T06:00:00Z", Schedule: pagerduty.Schedule{APIObject: pagerduty.APIObject{ID: "PSCHED1"}}, User: pagerduty.User{APIObject: pagerduty.APIObject{ID: "PUSER03", Summary: "Carol"}}},
+		// direct user target, no schedule
+		{EscalationLevel: 2, User: pagerduty.User{APIObject: pagerduty.APIObject{ID: "PUSER02", Summary: "Bob"}}},
+	}
+	return newEscalationPolicyGraph(ep, oncalls, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+}
+
+func TestNewEscalationPolicyGraph(t *testing.T) {
+	g := testEscalationPolicyGraph()
+	if len(g.Levels) != 2 {
+		t.Fatalf("got %d levels, want 2", len(g.Levels))
+	}
+	primary := g.Levels[0].Targets[0]
+	if len(primary.Oncall) != 1 || primary.Oncall[0].ID != "PUSER01" {
+		t.Fatalf("got oncall %+v for the schedule target, want only PUSER01", primary.Oncall)
+	}
+	bob := g.Levels[1].Targets[0]
+	if len(bob.Oncall) != 1 || bob.Oncall[0].ID != "PUSER02" {
+		t.Fatalf("got oncall %+v for the user target, want PUSER02", bob.Oncall)
+	}
+}
+
+func TestRenderEscalationPolicy(t *testing.T) {
+	graphs := []*escalationPolicyGraph{testEscalationPolicyGraph()}
+
+	var tree strings.Builder
+	renderEscalationPolicyTree(&tree, graphs)
+	wantTree := `Payments (repeats 2 times)
+├── services
+│   └── payments-api
+└── levels
+    ├── level 1 (escalates after 30 min)
+    │   └── schedule: Primary
+    │       └── oncall: Alice
+    └── level 2 (escalates after 15 min)
+        └── user: Bob
+`
+	if tree.String() != wantTree {
+		t.Fatalf("got tree:\n%s\nwant:\n%s", tree.String(), wantTree)
+	}
+
+	for name, tc := range map[string]struct {
+		render func(w *strings.Builder)
+		want   []string
+	}{
+		"dot": {
+			render: func(w *strings.Builder) { renderEscalationPolicyDOT(w, graphs) },
+			want: []string{
+				"service_PSVC001 -> ep_PEP0001;",
+				"ep_PEP0001_level_1 -> ep_PEP0001_level_2 [style=dashed",
+				`schedule_PSCHED1 -> user_PUSER01 [label="oncall"];`,
+				"ep_PEP0001_level_2 -> user_PUSER02;",
+			},
+		},
+		"mermaid": {
+			render: func(w *strings.Builder) { renderEscalationPolicyMermaid(w, graphs) },
+			want: []string{
+				`service_PSVC001["payments-api"] --> ep_PEP0001{{"Payments<br/>repeats 2 times"}}`,
+				"ep_PEP0001_level_1 -. escalate .-> ep_PEP0001_level_2",
+				`schedule_PSCHED1 -- oncall --> user_PUSER01("Alice")`,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var out strings.Builder
+			tc.render(&out)
+			for _, w := range tc.want {
+				if !strings.Contains(out.String(), w) {
+					t.Errorf("output does not contain %q:\n%s", w, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestSimulateEscalation(t *testing.T) {
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	ep := pagerduty.EscalationPolicy{
+		NumLoops: 1,
+		EscalationRules: []pagerduty.EscalationRule{
+			{Delay: 30, Targets: []pagerduty.APIObject{{ID: "PSCHED1", Type: "schedule_reference", Summary: "Primary"}}},
+			{Delay: 15, Targets: []pagerduty.APIObject{{ID: "PUSER02", Type: "user_reference", Summary: "Bob"}}},
+		},
+	}
+	oncalls := []pagerduty.OnCall{
+		{EscalationLevel: 1, Start: "2026-10-19T06:00:00Z", End: "2026-10-19T12:45:00Z", Schedule: pagerduty.Schedule{APIObject: pagerduty.APIObject{ID: "PSCHED1"}}, User: pagerduty.User{APIObject: pagerduty.APIObject{ID: "PUSER01"}}},
+		{EscalationLevel: 1, Start: "2026-10-19T12:45:00Z", End: "2026-10-20T06:00:00Z", Schedule: pagerduty.Schedule{APIObject: pagerduty.APIObject{ID: "PSCHED1"}}, User: pagerduty.User{APIObject: pagerduty.APIObject{ID: "PUSER03"}}},
+	}
+	steps := simulateEscalation(ep, oncalls, at)
+	want := []struct {
+		offset time.Duration
+		loop   uint
+		level  int
+		user   string
+	}{
+		{0, 0, 1, "PUSER01"},
+		{30 * time.Minute, 0, 2, "PUSER02"},
+		// the shift changed in the meantime
+		{45 * time.Minute, 1, 1, "PUSER03"},
+		{75 * time.Minute, 1, 2, "PUSER02"},
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("got %d steps, want %d", len(steps), len(want))
+	}
+	for idx, w := range want {
+		s := steps[idx]
+		if s.Offset != w.offset || s.Loop != w.loop || s.Level != w.level {
+			t.Errorf("step %d: got offset %s, loop %d, level %d; want %s, %d, %d", idx, s.Offset, s.Loop, s.Level, w.offset, w.loop, w.level)
+		}
+		if len(s.Targets) != 1 || len(s.Targets[0].Oncall) != 1 || s.Targets[0].Oncall[0].ID != w.user {
+			t.Errorf("step %d: got targets %+v, want %s oncall", idx, s.Targets, w.user)
+		}
+	}
+	if d := escalationDuration(&ep); d != 90*time.Minute {
+		t.Errorf("got escalation duration %s, want 1h30m", d)
+	}
+}
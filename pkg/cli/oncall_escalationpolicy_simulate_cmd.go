@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/ansi"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var flagOncallEscalationPolicySimulateAt string
+
+func init() {
+	OncallEscalationPolicyCmd.AddCommand(OncallEscalationPolicySimulateCmd)
+	OncallEscalationPolicySimulateCmd.Flags().StringVarP(&flagOncallEscalationPolicySimulateAt, "at", "t", "", "Time the simulated incident is triggered, either a date (YYYY-MM-DD, in the configured timezone) or any format accepted by the override command (default: now)")
+}
+
+var OncallEscalationPolicySimulateCmd = &cobra.Command{
+	Use:   "simulate <policy>",
+	Short: "Show who would be notified, and when, if an incident is not acknowledged (PagerDuty)",
+	Long: `Simulate an unacknowledged incident on an escalation policy.
+
+The timeline of notifications is computed from the escalation delay of each
+level and the number of times the policy repeats, and every level is resolved
+to the users that are oncall at the time it is notified. The timeline ends
+when the policy is exhausted.
+
+<policy> is either an escalation policy ID or a search query.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logrus.Debugf("Running oncall escalationpolicy simulate command")
+		ctx := context.Background()
+		cfg, err := GetConfig()
+		if err != nil {
+			return err
+		}
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return fmt.Errorf("cannot load timezone %q: %w", cfg.Timezone, err)
+		}
+		at := time.Now()
+		if flagOncallEscalationPolicySimulateAt != "" {
+			t, _, err := parseOverrideDateString(flagOncallEscalationPolicySimulateAt, loc)
+			if err != nil {
+				return fmt.Errorf("failed to parse --at: %w", err)
+			}
+			at = *t
+		}
+		at = at.In(loc)
+
+		client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+		ep, err := lookupEscalationPolicy(ctx, client, strings.Join(args, " "), true)
+		if err != nil {
+			return err
+		}
+		if len(ep.EscalationRules) == 0 {
+			return fmt.Errorf("escalation policy %q has no escalation rules", ep.Name)
+		}
+		end := at.Add(escalationDuration(ep))
+		oncalls, err := listOnCalls(ctx, client, pagerduty.ListOnCallOptions{
+			EscalationPolicyIDs: []string{ep.ID},
+			Includes:            []string{"users"},
+			Since:               at.Format(time.RFC3339),
+			Until:               end.Format(time.RFC3339),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get oncalls for escalation policy %q: %w", ep.Name, err)
+		}
+		steps := simulateEscalation(*ep, oncalls, at)
+
+		g := escalationPolicyGraph{Policy: *ep}
+		fmt.Printf("%s %s (%s)\n", ansi.Bold("Escalation policy:"), ansi.ToURL(ep.Name, ep.HTMLURL), g.repeatDescription())
+		fmt.Printf("Incident triggered at %s and never acknowledged\n", at.Format(shiftTimeFormat))
+		fmt.Println()
+		for _, s := range steps {
+			if s.Level == 1 && s.Loop > 0 {
+				fmt.Printf("%-7s %s  %s\n", formatEscalationOffset(s.Offset), s.At.Format(shiftTimeFormat),
+					ansi.Bold(fmt.Sprintf("repeat %d of %d", s.Loop, ep.NumLoops)))
+			}
+			fmt.Printf("%-7s %s  level %d\n", formatEscalationOffset(s.Offset), s.At.Format(shiftTimeFormat), s.Level)
+			for _, t := range s.Targets {
+				if !t.isScheduleTarget() {
+					fmt.Printf("            user %s\n", formatOncallUser(t.Oncall[0]))
+					continue
+				}
+				if len(t.Oncall) == 0 {
+					fmt.Printf("            schedule %s: %s\n", ansi.ToURL(t.Target.Summary, t.Target.HTMLURL), color.YellowString("nobody oncall"))
+					continue
+				}
+				for _, u := range t.Oncall {
+					fmt.Printf("            schedule %s: %s\n", ansi.ToURL(t.Target.Summary, t.Target.HTMLURL), formatOncallUser(u))
+				}
+			}
+		}
+		fmt.Printf("%-7s %s  %s\n", formatEscalationOffset(end.Sub(at)), end.Format(shiftTimeFormat),
+			ansi.Bold("policy exhausted, nobody else will be notified"))
+		return nil
+	},
+}
+
+// escalationStep is the notification of an escalation level during a
+// simulated escalation. Loop is 0 for the first pass through the policy, and
+// counts the repetitions afterwards.
+type escalationStep struct {
+	At      time.Time
+	Offset  time.Duration
+	Loop    uint
+	Level   int
+	Targets []escalationTarget
+}
+
+// escalationDuration returns how long it takes for an unacknowledged
+// incident to go through every level of ep, including its repetitions.
+func escalationDuration(ep *pagerduty.EscalationPolicy) time.Duration {
+	var pass time.Duration
+	for _, r := range ep.EscalationRules {
+		pass += time.Duration(r.Delay) * time.Minute
+	}
+	return pass * time.Duration(ep.NumLoops+1)
+}
+
+// simulateEscalation returns the notifications of an incident triggered at
+// the given time on ep and never acknowledged, resolving each level's targets
+// with the oncall entries of the policy.
+func simulateEscalation(ep pagerduty.EscalationPolicy, oncalls []pagerduty.OnCall, at time.Time) []escalationStep {
+	var (
+		steps  []escalationStep
+		offset time.Duration
+	)
+	for loop := uint(0); loop <= ep.NumLoops; loop++ {
+		for idx, r := range ep.EscalationRules {
+			t := at.Add(offset)
+			steps = append(steps, escalationStep{
+				At:      t,
+				Offset:  offset,
+				Loop:    loop,
+				Level:   idx + 1,
+				Targets: levelTargets(r.Targets, idx+1, oncalls, t),
+			})
+			offset += time.Duration(r.Delay) * time.Minute
+		}
+	}
+	return steps
+}
+
+// formatEscalationOffset formats the time since the incident was triggered,
+// in minutes.
+func formatEscalationOffset(d time.Duration) string {
+	return fmt.Sprintf("+%dm", int(d.Minutes()))
+}
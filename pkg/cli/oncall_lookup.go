@@ -0,0 +1,350 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/sirupsen/logrus"
+)
+
+// lookupUser searches PagerDuty users matching query and returns the only
+// match. If several users match, an exact (case-insensitive) match on the
+// e-mail address or name wins, otherwise the user is asked to pick one if
+// interactive is set, or an error is returned.
+func lookupUser(ctx context.Context, client *pagerduty.Client, query string, interactive bool) (*pagerduty.User, error) {
+	opts := pagerduty.ListUsersOptions{
+		Query:    query,
+		Includes: []string{"contact_methods"},
+	}
+	resp, err := client.ListUsersWithContext(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users matching %q: %w", query, err)
+	}
+	if len(resp.Users) == 0 {
+		return nil, fmt.Errorf("no user found matching %q", query)
+	}
+	for idx, u := range resp.Users {
+		if strings.EqualFold(u.Email, query) || strings.EqualFold(u.Name, query) {
+			return &resp.Users[idx], nil
+		}
+	}
+	items := make([]string, 0, len(resp.Users))
+	for _, u := range resp.Users {
+		items = append(items, fmt.Sprintf("%s <%s> %s", u.Name, u.Email, u.JobTitle))
+	}
+	if !interactive && len(items) > 1 {
+		return nil, fmt.Errorf("%d users match %q: %s", len(items), query, strings.Join(items, ", "))
+	}
+	idx, err := pick(fmt.Sprintf("users matching %q", query), items)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Users[idx], nil
+}
+
+// pagerDutyIDRegexp matches PagerDuty object IDs, like "P1ABCDE". Some names
+// look like IDs too, e.g. "PRIMARY", so lookups fall back to searching when
+// no object has such an ID.
+var pagerDutyIDRegexp = regexp.MustCompile(`^P[A-Z0-9]{6}$`)
+
+// isNotFound reports whether err is a 404 from PagerDuty's API.
+func isNotFound(err error) bool {
+	var aerr pagerduty.APIError
+	return errors.As(err, &aerr) && aerr.NotFound()
+}
+
+// resolveScheduleID returns s if it is the ID of a PagerDuty schedule,
+// otherwise it searches schedules matching s and returns the ID of the only
+// match, or of the one picked by the user.
+func resolveScheduleID(ctx context.Context, client *pagerduty.Client, s string) (string, error) {
+	if pagerDutyIDRegexp.MatchString(s) {
+		_, err := client.GetScheduleWithContext(ctx, s, pagerduty.GetScheduleOptions{})
+		if err == nil {
+			return s, nil
+		}
+		if !isNotFound(err) {
+			return "", fmt.Errorf("failed to get schedule with ID %q: %w", s, err)
+		}
+	}
+	resp, err := client.ListSchedulesWithContext(ctx, pagerduty.ListSchedulesOptions{Query: s})
+	if err != nil {
+		return "", fmt.Errorf("failed to search schedules matching %q: %w", s, err)
+	}
+	if len(resp.Schedules) == 0 {
+		return "", fmt.Errorf("no schedule found matching %q", s)
+	}
+	items := make([]string, 0, len(resp.Schedules))
+	for _, sc := range resp.Schedules {
+		if strings.EqualFold(sc.Name, s) {
+			return sc.ID, nil
+		}
+		items = append(items, fmt.Sprintf("%s (ID: %s)", sc.Name, sc.ID))
+	}
+	idx, err := pick(fmt.Sprintf("schedules matching %q", s), items)
+	if err != nil {
+		return "", err
+	}
+	return resp.Schedules[idx].ID, nil
+}
+
+// listOnCalls fetches all the oncall entries matching opts, following
+// pagination.
+func listOnCalls(ctx context.Context, client *pagerduty.Client, opts pagerduty.ListOnCallOptions) ([]pagerduty.OnCall, error) {
+	if opts.Limit == 0 {
+		opts.Limit = 100 // 100 is the maximum allowed by PagerDuty's API
+	}
+	var oncalls []pagerduty.OnCall
+	for {
+		resp, err := client.ListOnCallsWithContext(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		oncalls = append(oncalls, resp.OnCalls...)
+		if !resp.More || len(resp.OnCalls) == 0 {
+			break
+		}
+		opts.Offset += uint(len(resp.OnCalls))
+	}
+	return oncalls, nil
+}
+
+// lookupEscalationPolicy returns the escalation policy with ID s, or searches
+// escalation policies matching s and returns the only match. If several
+// policies match, an exact (case-insensitive) match on the name wins,
+// otherwise the user is asked to pick one if interactive is set, or an error
+// is returned. Lookups running concurrently must not be interactive.
+func lookupEscalationPolicy(ctx context.Context, client *pagerduty.Client, s string, interactive bool) (*pagerduty.EscalationPolicy, error) {
+	includes := []string{"services", "targets", "teams"}
+	if pagerDutyIDRegexp.MatchString(s) {
+		ep, err := client.GetEscalationPolicyWithContext(ctx, s, &pagerduty.GetEscalationPolicyOptions{Includes: includes})
+		if err == nil {
+			return ep, nil
+		}
+		if !isNotFound(err) {
+			return nil, fmt.Errorf("failed to get escalation policy with ID %q: %w", s, err)
+		}
+	}
+	resp, err := client.ListEscalationPoliciesWithContext(ctx, pagerduty.ListEscalationPoliciesOptions{Query: s, Includes: includes})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search escalation policies matching %q: %w", s, err)
+	}
+	if len(resp.EscalationPolicies) == 0 {
+		return nil, fmt.Errorf("no escalation policy found matching %q", s)
+	}
+	items := make([]string, 0, len(resp.EscalationPolicies))
+	for idx, ep := range resp.EscalationPolicies {
+		if strings.EqualFold(ep.Name, s) {
+			return &resp.EscalationPolicies[idx], nil
+		}
+		items = append(items, fmt.Sprintf("%s (ID: %s)", ep.Name, ep.ID))
+	}
+	if !interactive && len(items) > 1 {
+		return nil, fmt.Errorf("%d escalation policies match %q: %s", len(items), s, strings.Join(items, ", "))
+	}
+	idx, err := pick(fmt.Sprintf("escalation policies matching %q", s), items)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.EscalationPolicies[idx], nil
+}
+
+// resolveTeamIDs returns the IDs of the teams configured in
+// `pagerduty.teams`. Entries starting with "+" are team IDs, the others are
+// queries matching team names.
+func resolveTeamIDs(ctx context.Context, client *pagerduty.Client, teams []string) ([]string, error) {
+	seen := make(map[string]struct{})
+	var teamIDs []string
+	add := func(id string) {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			teamIDs = append(teamIDs, id)
+		}
+	}
+	for _, teamStr := range teams {
+		if teamStr == "" {
+			continue
+		}
+		if teamStr[0] == '+' {
+			add(teamStr[1:])
+			continue
+		}
+		// this is a team name, not a team ID, fetch the team ID
+		resp, err := client.ListTeamsWithContext(ctx, pagerduty.ListTeamOptions{Query: teamStr})
+		if err != nil {
+			logrus.Warningf("Failed to get team with pattern %q, skipping. Error was: %v", teamStr, err)
+			continue
+		}
+		for _, team := range resp.Teams {
+			add(team.ID)
+		}
+	}
+	if len(teamIDs) == 0 {
+		return nil, fmt.Errorf("no teams found matching %q", teams)
+	}
+	return teamIDs, nil
+}
+
+// listEscalationPolicies fetches all the escalation policies matching opts,
+// following pagination.
+func listEscalationPolicies(ctx context.Context, client *pagerduty.Client, opts pagerduty.ListEscalationPoliciesOptions) ([]pagerduty.EscalationPolicy, error) {
+	if opts.Limit == 0 {
+		opts.Limit = 100 // 100 is the maximum allowed by PagerDuty's API
+	}
+	var policies []pagerduty.EscalationPolicy
+	for {
+		resp, err := client.ListEscalationPoliciesWithContext(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, resp.EscalationPolicies...)
+		if !resp.More || len(resp.EscalationPolicies) == 0 {
+			break
+		}
+		opts.Offset += uint(len(resp.EscalationPolicies))
+	}
+	return policies, nil
+}
+
+// listTeamUsers returns the members of the teams, with their contact methods
+// and notification rules, sorted by name. Members of several teams are only
+// returned once.
+func listTeamUsers(ctx context.Context, client *pagerduty.Client, teamIDs []string) ([]*pagerduty.User, error) {
+	userIDs := make(map[string]struct{})
+	for _, teamID := range teamIDs {
+		members, err := client.ListTeamMembersPaginated(ctx, teamID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get members of team %q: %w", teamID, err)
+		}
+		for _, m := range members {
+			userIDs[m.User.ID] = struct{}{}
+		}
+	}
+	users := make([]*pagerduty.User, 0, len(userIDs))
+	for userID := range userIDs {
+		u, err := client.GetUserWithContext(ctx, userID, pagerduty.GetUserOptions{
+			Includes: []string{"contact_methods", "notification_rules"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user %q: %w", userID, err)
+		}
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	return users, nil
+}
+
+// lookupTeam resolves a team the same way as the `pagerduty.teams` config:
+// "+ID" is a team ID, anything else is a query matching team names. If several
+// teams match, an exact (case-insensitive) match on the name wins, otherwise
+// the user is asked to pick one.
+func lookupTeam(ctx context.Context, client *pagerduty.Client, s string) (*pagerduty.Team, error) {
+	if strings.HasPrefix(s, "+") {
+		team, err := client.GetTeamWithContext(ctx, s[1:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get team with ID %q: %w", s[1:], err)
+		}
+		return team, nil
+	}
+	resp, err := client.ListTeamsWithContext(ctx, pagerduty.ListTeamOptions{Query: s})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search teams matching %q: %w", s, err)
+	}
+	if len(resp.Teams) == 0 {
+		return nil, fmt.Errorf("no team found matching %q", s)
+	}
+	items := make([]string, 0, len(resp.Teams))
+	for idx, t := range resp.Teams {
+		if strings.EqualFold(t.Name, s) {
+			return &resp.Teams[idx], nil
+		}
+		items = append(items, fmt.Sprintf("%s (ID: %s)", t.Name, t.ID))
+	}
+	idx, err := pick(fmt.Sprintf("teams matching %q", s), items)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Teams[idx], nil
+}
+
+// listSchedules fetches all the schedules matching opts, following
+// pagination.
+func listSchedules(ctx context.Context, client *pagerduty.Client, opts pagerduty.ListSchedulesOptions) ([]pagerduty.Schedule, error) {
+	if opts.Limit == 0 {
+		opts.Limit = 100 // 100 is the maximum allowed by PagerDuty's API
+	}
+	var schedules []pagerduty.Schedule
+	for {
+		resp, err := client.ListSchedulesWithContext(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, resp.Schedules...)
+		if !resp.More || len(resp.Schedules) == 0 {
+			break
+		}
+		opts.Offset += uint(len(resp.Schedules))
+	}
+	return schedules, nil
+}
+
+// listIncidents fetches all the incidents matching opts, following
+// pagination.
+func listIncidents(ctx context.Context, client *pagerduty.Client, opts pagerduty.ListIncidentsOptions) ([]pagerduty.Incident, error) {
+	if opts.Limit == 0 {
+		opts.Limit = 100 // 100 is the maximum allowed by PagerDuty's API
+	}
+	var incidents []pagerduty.Incident
+	for {
+		resp, err := client.ListIncidentsWithContext(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, resp.Incidents...)
+		if !resp.More || len(resp.Incidents) == 0 {
+			break
+		}
+		opts.Offset += uint(len(resp.Incidents))
+	}
+	return incidents, nil
+}
+
+// lookupService returns the service with ID s, or searches the services of
+// the given teams matching s and returns the only match. If several services
+// match, an exact (case-insensitive) match on the name wins, otherwise the
+// user is asked to pick one.
+func lookupService(ctx context.Context, client *pagerduty.Client, teamIDs []string, s string) (*pagerduty.Service, error) {
+	includes := []string{"escalation_policies", "integrations", "teams"}
+	if pagerDutyIDRegexp.MatchString(s) {
+		service, err := client.GetServiceWithContext(ctx, s, &pagerduty.GetServiceOptions{Includes: includes})
+		if err == nil {
+			return service, nil
+		}
+		if !isNotFound(err) {
+			return nil, fmt.Errorf("failed to get service with ID %q: %w", s, err)
+		}
+	}
+	services, err := client.ListServicesPaginated(ctx, pagerduty.ListServiceOptions{Query: s, TeamIDs: teamIDs, Includes: includes})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search services matching %q: %w", s, err)
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("no service found matching %q", s)
+	}
+	items := make([]string, 0, len(services))
+	for idx, svc := range services {
+		if strings.EqualFold(svc.Name, s) {
+			return &services[idx], nil
+		}
+		items = append(items, fmt.Sprintf("%s (ID: %s)", svc.Name, svc.ID))
+	}
+	idx, err := pick(fmt.Sprintf("services matching %q", s), items)
+	if err != nil {
+		return nil, err
+	}
+	return &services[idx], nil
+}
@@ -0,0 +1,368 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/ansi"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var flagOncallOverrideApplyFile string
+
+func init() {
+	OncallOverrideCmd.AddCommand(OncallOverrideApplyCmd)
+	OncallOverrideApplyCmd.Flags().StringVarP(&flagOncallOverrideApplyFile, "file", "f", "", "YAML or CSV file with the overrides to create")
+}
+
+var OncallOverrideApplyCmd = &cobra.Command{
+	Use:   "apply -f <file>",
+	Short: "Create overrides in bulk from a YAML or CSV file (PagerDuty)",
+	Long: `Create overrides in bulk from a YAML or CSV file.
+
+The YAML format is a list of overrides:
+
+  - schedule: PXXXXXX
+    user: alice@example.com
+    start: 2026-12-24T09:00:00+01:00
+    end: 2026-12-26T09:00:00+01:00
+
+Files ending in .csv are read as CSV with a "schedule,user,start,end" header.
+An empty schedule defaults to ` + "`oncall.default_schedule`" + `. Times accept the same
+formats as the ` + "`override`" + ` subcommand.
+
+All the rows are validated before anything is created: users must resolve to
+exactly one PagerDuty user, end must be after start, and overrides on the same
+schedule must not overlap. Overrides that already exist with the same user and
+times are skipped, so applying the same file again is safe. The safety checks of
+the ` + "`override`" + ` command run on each override to create, and failures need --force.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logrus.Debugf("Running oncall override apply command")
+		ctx := context.Background()
+		cfg, err := GetConfig()
+		if err != nil {
+			return err
+		}
+		if flagOncallOverrideApplyFile == "" {
+			return fmt.Errorf("no file specified, use --file")
+		}
+		fd, err := os.Open(flagOncallOverrideApplyFile)
+		if err != nil {
+			return fmt.Errorf("failed to open overrides file: %w", err)
+		}
+		defer func() {
+			if err := fd.Close(); err != nil {
+				logrus.Warningf("Failed to close overrides file: %v", err)
+			}
+		}()
+		isCSV := strings.EqualFold(filepath.Ext(flagOncallOverrideApplyFile), ".csv")
+		rows, err := parseOverrideRows(fd, isCSV)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", flagOncallOverrideApplyFile, err)
+		}
+		if len(rows) == 0 {
+			fmt.Printf("No overrides in %s\n", flagOncallOverrideApplyFile)
+			return nil
+		}
+		planned, err := planOverrideRows(rows, cfg.Oncall.DefaultSchedule)
+		if err != nil {
+			return err
+		}
+
+		// resolve all the users up front, so that a typo doesn't leave the
+		// file half-applied. Ambiguous users are errors rather than prompts,
+		// since each row must resolve to exactly one user
+		client := pagerduty.NewClient(cfg.PagerDuty.UserToken, withRateLimitRetries())
+		users := make(map[string]*pagerduty.User)
+		var errs []string
+		for idx := range planned {
+			p := &planned[idx]
+			u, ok := users[p.UserQuery]
+			if !ok {
+				u, err = lookupUser(ctx, client, p.UserQuery, false)
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("row %d: %v", p.Row, err))
+					continue
+				}
+				users[p.UserQuery] = u
+			}
+			p.User = u
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("invalid overrides:\n%s", strings.Join(errs, "\n"))
+		}
+
+		// skip overrides that already exist, to make re-runs idempotent
+		bySchedule := make(map[string][]*plannedOverride)
+		for idx := range planned {
+			bySchedule[planned[idx].ScheduleID] = append(bySchedule[planned[idx].ScheduleID], &planned[idx])
+		}
+		for scheduleID, ps := range bySchedule {
+			since, until := ps[0].Start, ps[0].End
+			for _, p := range ps {
+				if p.Start.Before(since) {
+					since = p.Start
+				}
+				if p.End.After(until) {
+					until = p.End
+				}
+			}
+			resp, err := client.ListOverridesWithContext(ctx, scheduleID, pagerduty.ListOverridesOptions{
+				Since: since.Format(time.RFC3339),
+				Until: until.Format(time.RFC3339),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to list existing overrides for schedule %q: %w", scheduleID, err)
+			}
+			for _, p := range ps {
+				p.Exists = overrideExists(resp.Overrides, p)
+			}
+		}
+
+		var todo int
+		fmt.Printf("%s:\n", ansi.Bold("Overrides"))
+		for _, p := range planned {
+			status := "create"
+			if p.Exists {
+				status = "exists, skipping"
+			} else {
+				todo++
+			}
+			fmt.Printf("    %d) %s %s - %s\t%s <%s>\t[%s]\n",
+				p.Row, p.ScheduleID, p.Start.Format(shiftTimeFormat), p.End.Format(shiftTimeFormat), p.User.Name, p.User.Email, status)
+		}
+		if todo == 0 {
+			fmt.Printf("All %d overrides already exist, nothing to do\n", len(planned))
+			return nil
+		}
+		fmt.Println()
+		var overrides []checkedOverride
+		for _, p := range planned {
+			if p.Exists {
+				continue
+			}
+			overrides = append(overrides, checkedOverride{
+				Label:      fmt.Sprintf("Row %d", p.Row),
+				User:       p.User,
+				ScheduleID: p.ScheduleID,
+				Start:      p.Start,
+				End:        p.End,
+			})
+		}
+		hard, err := checkOverrides(ctx, client, &cfg.Oncall.OverrideChecks, overrides)
+		if err != nil {
+			return err
+		}
+		if hard > 0 && !flagOncallOverrideForce {
+			if flagOncallOverrideDryRun {
+				fmt.Printf("Dry run, %d overrides not created. They would need --force because of %d failed checks\n", todo, hard)
+				return nil
+			}
+			return fmt.Errorf("%d safety checks failed, use --force to create the overrides anyway", hard)
+		}
+		if flagOncallOverrideDryRun {
+			fmt.Printf("Dry run, %d overrides not created\n", todo)
+			return nil
+		}
+		if !flagOncallOverrideYes {
+			ok, err := confirm(fmt.Sprintf("Do you want to create %d overrides?", todo))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Printf("\nAborting\n")
+				return nil
+			}
+		}
+
+		var created, failed int
+		for _, p := range planned {
+			if p.Exists {
+				continue
+			}
+			override := pagerduty.Override{
+				User:  p.User.APIObject,
+				Start: p.Start.Format(time.RFC3339),
+				End:   p.End.Format(time.RFC3339),
+			}
+			if _, err := client.CreateOverrideWithContext(ctx, p.ScheduleID, override); err != nil {
+				fmt.Printf("Row %d: override creation failed: %v\n", p.Row, err)
+				failed++
+				continue
+			}
+			created++
+		}
+		fmt.Printf("Created %d, skipped %d existing, failed %d\n", created, len(planned)-todo, failed)
+		if failed > 0 {
+			return fmt.Errorf("%d overrides could not be created", failed)
+		}
+		return nil
+	},
+}
+
+// overrideRow is a single override as read from a YAML or CSV file, before
+// validation.
+type overrideRow struct {
+	Schedule string `yaml:"schedule"`
+	User     string `yaml:"user"`
+	Start    string `yaml:"start"`
+	End      string `yaml:"end"`
+}
+
+// plannedOverride is a validated override row.
+type plannedOverride struct {
+	// Row is the 1-based position of the override in the input file.
+	Row        int
+	ScheduleID string
+	UserQuery  string
+	Start      time.Time
+	End        time.Time
+	// User is set once UserQuery has been resolved.
+	User *pagerduty.User
+	// Exists is set if an identical override is already in PagerDuty.
+	Exists bool
+}
+
+// parseOverrideRows reads override rows from r, either as a YAML list or as
+// CSV with a "schedule,user,start,end" header.
+func parseOverrideRows(r io.Reader, isCSV bool) ([]overrideRow, error) {
+	if !isCSV {
+		var rows []overrideRow
+		if err := yaml.NewDecoder(r).Decode(&rows); err != nil {
+			if err == io.EOF {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return rows, nil
+	}
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	columns := make(map[string]int)
+	for idx, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = idx
+	}
+	for _, name := range []string{"schedule", "user", "start", "end"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing %q column in CSV header", name)
+		}
+	}
+	rows := make([]overrideRow, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		rows = append(rows, overrideRow{
+			Schedule: strings.TrimSpace(rec[columns["schedule"]]),
+			User:     strings.TrimSpace(rec[columns["user"]]),
+			Start:    strings.TrimSpace(rec[columns["start"]]),
+			End:      strings.TrimSpace(rec[columns["end"]]),
+		})
+	}
+	return rows, nil
+}
+
+// planOverrideRows validates the rows and parses their times. All the problems
+// are reported at once rather than stopping at the first one.
+func planOverrideRows(rows []overrideRow, defaultSchedule string) ([]plannedOverride, error) {
+	var (
+		planned = make([]plannedOverride, 0, len(rows))
+		errs    []string
+	)
+	for idx, r := range rows {
+		p := plannedOverride{Row: idx + 1, ScheduleID: r.Schedule, UserQuery: r.User}
+		if p.ScheduleID == "" {
+			p.ScheduleID = defaultSchedule
+		}
+		if p.ScheduleID == "" {
+			errs = append(errs, fmt.Sprintf("row %d: no schedule specified", p.Row))
+		}
+		if p.UserQuery == "" {
+			errs = append(errs, fmt.Sprintf("row %d: no user specified", p.Row))
+		}
+		start, err := parseOverrideTimeString(r.Start)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("row %d: invalid start: %v", p.Row, err))
+			continue
+		}
+		end, err := parseOverrideTimeString(r.End)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("row %d: invalid end: %v", p.Row, err))
+			continue
+		}
+		if !end.After(*start) {
+			errs = append(errs, fmt.Sprintf("row %d: end time must be after start time", p.Row))
+			continue
+		}
+		p.Start, p.End = *start, *end
+		planned = append(planned, p)
+	}
+
+	// overrides on the same schedule must not overlap
+	bySchedule := make(map[string][]plannedOverride)
+	for _, p := range planned {
+		bySchedule[p.ScheduleID] = append(bySchedule[p.ScheduleID], p)
+	}
+	scheduleIDs := make([]string, 0, len(bySchedule))
+	for scheduleID := range bySchedule {
+		scheduleIDs = append(scheduleIDs, scheduleID)
+	}
+	sort.Strings(scheduleIDs)
+	for _, scheduleID := range scheduleIDs {
+		ps := bySchedule[scheduleID]
+		sort.Slice(ps, func(i, j int) bool { return ps[i].Start.Before(ps[j].Start) })
+		// compare each row with the one ending last so far, which may not be
+		// the previous one
+		last := 0
+		for i := 1; i < len(ps); i++ {
+			if ps[i].Start.Before(ps[last].End) {
+				errs = append(errs, fmt.Sprintf("rows %d and %d overlap on schedule %s", ps[last].Row, ps[i].Row, scheduleID))
+			}
+			if ps[i].End.After(ps[last].End) {
+				last = i
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid overrides:\n%s", strings.Join(errs, "\n"))
+	}
+	return planned, nil
+}
+
+// overrideExists reports whether one of the existing overrides has the same
+// user and time range as p.
+func overrideExists(existing []pagerduty.Override, p *plannedOverride) bool {
+	for _, o := range existing {
+		if o.User.ID != p.User.ID {
+			continue
+		}
+		start, err := time.Parse(time.RFC3339, o.Start)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(time.RFC3339, o.End)
+		if err != nil {
+			continue
+		}
+		if start.Equal(p.Start) && end.Equal(p.End) {
+			return true
+		}
+	}
+	return false
+}
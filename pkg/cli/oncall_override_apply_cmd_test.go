@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOverrideRows(t *testing.T) {
+	yamlInput := `
+- schedule: PSCHED1
+  user: alice@example.com
+  start: 2026-12-24T09:00:00+01:00
+  end: 2026-12-25T09:00:00+01:00
+- user: bob
+  start: "2026-12-25T09:00:00+01:00"
+  end: "2026-12-26T09:00:00+01:00"
+`
+	csvInput := `schedule, user, start, end
+PSCHED1, alice@example.com, 2026-12-24T09:00:00+01:00, 2026-12-25T09:00:00+01:00
+, bob, 2026-12-25T09:00:00+01:00, 2026-12-26T09:00:00+01:00
+`
+	for name, tc := range map[string]struct {
+		input string
+		isCSV bool
+	}{
+		"yaml": {input: yamlInput},
+		"csv":  {input: csvInput, isCSV: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rows, err := parseOverrideRows(strings.NewReader(tc.input), tc.isCSV)
+			if err != nil {
+				t.Fatalf("parseOverrideRows: %v", err)
+			}
+			if len(rows) != 2 {
+				t.Fatalf("got %d rows, want 2", len(rows))
+			}
+			want := overrideRow{Schedule: "PSCHED1", User: "alice@example.com", Start: "2026-12-24T09:00:00+01:00", End: "2026-12-25T09:00:00+01:00"}
+			if rows[0] != want {
+				t.Fatalf("got %+v, want %+v", rows[0], want)
+			}
+			planned, err := planOverrideRows(rows, "PDEFAULT")
+			if err != nil {
+				t.Fatalf("planOverrideRows: %v", err)
+			}
+			if planned[1].ScheduleID != "PDEFAULT" {
+				t.Fatalf("got schedule %q for row 2, want the default schedule", planned[1].ScheduleID)
+			}
+		})
+	}
+}
+
+func TestPlanOverrideRowsValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    []overrideRow
+		wantErr string
+	}{
+		{
+			name: "end before start",
+			rows: []overrideRow{
+				{Schedule: "P1", User: "alice", Start: "2026-12-25T09:00:00Z", End: "2026-12-24T09:00:00Z"},
+			},
+			wantErr: "row 1: end time must be after start time",
+		},
+		{
+			name: "overlap on same schedule",
+			rows: []overrideRow{
+				{Schedule: "P1", User: "alice", Start: "2026-12-24T09:00:00Z", End: "2026-12-25T09:00:00Z"},
+				{Schedule: "P1", User: "bob", Start: "2026-12-25T08:00:00Z", End: "2026-12-26T09:00:00Z"},
+			},
+			wantErr: "rows 1 and 2 overlap on schedule P1",
+		},
+		{
+			name: "overlap with an earlier row that is not adjacent",
+			rows: []overrideRow{
+				{Schedule: "P1", User: "alice", Start: "2026-12-24T00:00:00Z", End: "2026-12-24T12:00:00Z"},
+				{Schedule: "P1", User: "bob", Start: "2026-12-24T01:00:00Z", End: "2026-12-24T02:00:00Z"},
+				{Schedule: "P1", User: "carol", Start: "2026-12-24T03:00:00Z", End: "2026-12-24T04:00:00Z"},
+			},
+			wantErr: "rows 1 and 3 overlap on schedule P1",
+		},
+		{
+			name: "no schedule and no default",
+			rows: []overrideRow{
+				{User: "alice", Start: "2026-12-24T09:00:00Z", End: "2026-12-25T09:00:00Z"},
+			},
+			wantErr: "row 1: no schedule specified",
+		},
+		{
+			name: "adjacent and different schedules are fine",
+			rows: []overrideRow{
+				{Schedule: "P1", User: "alice", Start: "2026-12-24T09:00:00Z", End: "2026-12-25T09:00:00Z"},
+				{Schedule: "P1", User: "bob", Start: "2026-12-25T09:00:00Z", End: "2026-12-26T09:00:00Z"},
+				{Schedule: "P2", User: "carol", Start: "2026-12-24T10:00:00Z", End: "2026-12-25T09:00:00Z"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := planOverrideRows(tc.rows, "")
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("got error %v, want it to contain %q", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/config"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/fatih/color"
+)
+
+// overrideCheck is the outcome of a failed safety check on an override. Hard
+// failures block the override unless --force is passed, the others are just
+// warnings.
+type overrideCheck struct {
+	Hard    bool
+	Message string
+}
+
+// checkOverride runs the safety checks on an override of scheduleID by user
+// from start to end: the override must not be too short, the user must be
+// reachable by phone, SMS or push, and must not be oncall in another schedule
+// at the same time. Overrides in the user's night hours are reported as
+// warnings.
+func checkOverride(ctx context.Context, client *pagerduty.Client, checks *config.OverrideChecksConfig, user *pagerduty.User, scheduleID string, start, end time.Time) ([]overrideCheck, error) {
+	var out []overrideCheck
+
+	if min := checks.MinOverrideDuration(); end.Sub(start) < min {
+		out = append(out, overrideCheck{
+			Hard:    true,
+			Message: fmt.Sprintf("the override lasts %s, less than the minimum of %s", end.Sub(start), min),
+		})
+	}
+
+	if !hasRealtimeContact(user.ContactMethods) {
+		out = append(out, overrideCheck{
+			Hard:    true,
+			Message: fmt.Sprintf("%s has no phone, SMS or push contact method and may not notice a page", user.Name),
+		})
+	}
+
+	nightStartStr, nightEndStr := checks.NightHours()
+	nightStart, err := parseClockTime(nightStartStr)
+	if err != nil {
+		return nil, err
+	}
+	nightEnd, err := parseClockTime(nightEndStr)
+	if err != nil {
+		return nil, err
+	}
+	// an empty time zone would load as UTC, so treat it as unknown
+	loc, err := time.LoadLocation(user.Timezone)
+	if user.Timezone == "" || err != nil {
+		out = append(out, overrideCheck{
+			Message: fmt.Sprintf("cannot check night hours, unknown time zone %q for %s", user.Timezone, user.Name),
+		})
+	} else if overlapsNight(start, end, loc, nightStart, nightEnd) {
+		out = append(out, overrideCheck{
+			Message: fmt.Sprintf("the override falls in the night hours (%s-%s) of %s, whose time zone is %s (%s - %s local time)",
+				nightStartStr, nightEndStr, user.Name, user.Timezone, start.In(loc).Format(shiftTimeFormat), end.In(loc).Format(shiftTimeFormat)),
+		})
+	}
+
+	oncalls, err := listOnCalls(ctx, client, pagerduty.ListOnCallOptions{
+		UserIDs: []string{user.ID},
+		Since:   start.Format(time.RFC3339),
+		Until:   end.Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oncalls of %s: %w", user.Name, err)
+	}
+	seen := make(map[string]struct{})
+	for _, oc := range oncalls {
+		if oc.Schedule.ID == "" || oc.Schedule.ID == scheduleID {
+			continue
+		}
+		if _, ok := seen[oc.Schedule.ID]; ok {
+			continue
+		}
+		seen[oc.Schedule.ID] = struct{}{}
+		out = append(out, overrideCheck{
+			Hard:    true,
+			Message: fmt.Sprintf("%s is already oncall in schedule %q from %s to %s", user.Name, oc.Schedule.Summary, oc.Start, oc.End),
+		})
+	}
+	return out, nil
+}
+
+// checkedOverride is one of several overrides to run the safety checks on,
+// e.g. an occurrence of a recurring override or a row of a file.
+type checkedOverride struct {
+	Label      string
+	User       *pagerduty.User
+	ScheduleID string
+	Start      time.Time
+	End        time.Time
+}
+
+// checkOverrides runs the safety checks on each override, concurrently, and
+// prints the failed ones under the label of their override. It returns how
+// many hard failures there are in total.
+func checkOverrides(ctx context.Context, client *pagerduty.Client, checks *config.OverrideChecksConfig, overrides []checkedOverride) (int, error) {
+	results := make([][]overrideCheck, len(overrides))
+	errs := make([]error, len(overrides))
+	forEachConcurrently(defaultConcurrency, len(overrides), func(idx int) {
+		o := overrides[idx]
+		results[idx], errs[idx] = checkOverride(ctx, client, checks, o.User, o.ScheduleID, o.Start, o.End)
+	})
+	hard := 0
+	for idx, o := range overrides {
+		if errs[idx] != nil {
+			return 0, fmt.Errorf("%s: %w", o.Label, errs[idx])
+		}
+		if len(results[idx]) == 0 {
+			continue
+		}
+		fmt.Printf("%s:\n", o.Label)
+		hard += printOverrideChecks(results[idx])
+	}
+	return hard, nil
+}
+
+// printOverrideChecks prints the failed checks and returns how many of them
+// are hard failures.
+func printOverrideChecks(checks []overrideCheck) int {
+	hard := 0
+	for _, c := range checks {
+		if c.Hard {
+			hard++
+			fmt.Printf("%s %s\n", color.RedString("Error:"), c.Message)
+		} else {
+			fmt.Printf("%s %s\n", color.YellowString("Warning:"), c.Message)
+		}
+	}
+	return hard
+}
+
+// hasRealtimeContact reports whether any of the contact methods can wake
+// somebody up, i.e. is a phone, SMS or push notification one.
+func hasRealtimeContact(methods []pagerduty.ContactMethod) bool {
+	for _, m := range methods {
+		switch contactMethodType(m) {
+		case "phone_contact_method", "sms_contact_method", "push_notification_contact_method":
+			return true
+		}
+	}
+	return false
+}
+
+// overlapsNight reports whether the [start, end) range overlaps the night
+// hours in loc, between nightStart and nightEnd. The night can wrap around
+// midnight (e.g. 22:00-07:00).
+func overlapsNight(start, end time.Time, loc *time.Location, nightStart, nightEnd clockTime) bool {
+	first := start.In(loc).AddDate(0, 0, -1)
+	last := end.In(loc)
+	for day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc); !day.After(last); day = day.AddDate(0, 0, 1) {
+		ns := nightStart.on(day, loc)
+		ne := nightEnd.on(day, loc)
+		if !ne.After(ns) {
+			ne = nightEnd.on(day.AddDate(0, 0, 1), loc)
+		}
+		if ns.Before(end) && ne.After(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// contactMethodType returns the type of a contact method, whether it is the
+// full object or a reference to it (e.g. "email_contact_method_reference").
+func contactMethodType(m pagerduty.ContactMethod) string {
+	return strings.TrimSuffix(m.Type, "_reference")
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestOverlapsNight(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	nightStart, _ := parseClockTime("22:00")
+	nightEnd, _ := parseClockTime("07:00")
+	tests := []struct {
+		name       string
+		start, end string
+		want       bool
+	}{
+		{name: "working hours", start: "2026-03-10T14:00:00Z", end: "2026-03-10T21:00:00Z", want: false},
+		{name: "late evening", start: "2026-03-10T14:00:00Z", end: "2026-03-11T03:00:00Z", want: true},
+		{name: "early morning of the first day", start: "2026-03-10T10:00:00Z", end: "2026-03-10T12:00:00Z", want: true},
+		{name: "ends exactly when the night starts", start: "2026-03-10T20:00:00Z", end: "2026-03-11T02:00:00Z", want: false},
+		{name: "several days", start: "2026-03-10T14:00:00Z", end: "2026-03-13T14:00:00Z", want: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			start, err := time.Parse(time.RFC3339, tc.start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			end, err := time.Parse(time.RFC3339, tc.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := overlapsNight(start, end, loc, nightStart, nightEnd); got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHasRealtimeContact(t *testing.T) {
+	email := pagerduty.ContactMethod{Type: "email_contact_method"}
+	sms := pagerduty.ContactMethod{Type: "sms_contact_method"}
+	if hasRealtimeContact([]pagerduty.ContactMethod{email}) {
+		t.Fatalf("e-mail only should not be a realtime contact")
+	}
+	if !hasRealtimeContact([]pagerduty.ContactMethod{email, sms}) {
+		t.Fatalf("SMS should be a realtime contact")
+	}
+}
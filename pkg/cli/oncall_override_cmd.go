@@ -13,14 +13,18 @@ import (
 	"github.com/insomniacslk/sre/pkg/ansi"
 
 	"github.com/PagerDuty/go-pagerduty"
+	"github.com/fatih/color"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
-	flagOncallOverrideStart string
-	flagOncallOverrideEnd   string
-	flagOncallOverrideYes   bool
+	flagOncallOverrideStart  string
+	flagOncallOverrideEnd    string
+	flagOncallOverrideYes    bool
+	flagOncallOverrideDryRun bool
+	flagOncallOverrideForce  bool
 )
 
 func init() {
@@ -28,6 +32,19 @@ func init() {
 	OncallOverrideCmd.PersistentFlags().StringVarP(&flagOncallOverrideStart, "start-time", "s", "", "Start time of the override. Accepted formats: RFC3339, RFC3339Nano, RFC822, RFC822Z, Unix time")
 	OncallOverrideCmd.PersistentFlags().StringVarP(&flagOncallOverrideEnd, "end-time", "e", "", "End time of the override. Accepted formats: RFC3339, RFC3339Nano, RFC822, RFC822Z, Unix time")
 	OncallOverrideCmd.PersistentFlags().BoolVarP(&flagOncallOverrideYes, "yes", "y", false, "Do not ask for confirmation before creating the override")
+	OncallOverrideCmd.PersistentFlags().BoolVar(&flagOncallOverrideForce, "force", false, "Create the override even if some safety checks fail")
+	OncallOverrideCmd.PersistentFlags().BoolVarP(&flagOncallOverrideDryRun, "dry-run", "n", false, "Only show the schedule before and after the override, without creating it")
+	// accept --start and --end as shorter spellings of --start-time and
+	// --end-time, which read better with clock times in `override create`
+	OncallOverrideCmd.SetGlobalNormalizationFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		switch name {
+		case "start":
+			name = "start-time"
+		case "end":
+			name = "end-time"
+		}
+		return pflag.NormalizedName(name)
+	})
 }
 
 func parseOverrideTimeString(s string) (*time.Time, error) {
@@ -47,144 +64,189 @@ func parseOverrideTimeString(s string) (*time.Time, error) {
 	return nil, fmt.Errorf("time string %q not in any supported format", s)
 }
 
+// parseOverrideDateString parses either a plain date (YYYY-MM-DD, midnight in
+// loc) or any of the formats accepted by parseOverrideTimeString. The returned
+// bool reports whether s was a plain date.
+func parseOverrideDateString(s string, loc *time.Location) (*time.Time, bool, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, loc); err == nil {
+		return &t, true, nil
+	}
+	t, err := parseOverrideTimeString(s)
+	if err != nil {
+		return nil, false, err
+	}
+	return t, false, nil
+}
+
 var OncallOverrideCmd = &cobra.Command{
 	Use:     "override",
 	Aliases: []string{"o", "ov", "over"},
 	Short:   "Create an override in the oncall schedule (PagerDuty)",
 	Args:    cobra.MinimumNArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		logrus.Debugf("Running override command")
-		ctx := context.Background()
-		cfg, err := GetConfig()
-		if err != nil {
-			return err
-		}
+	RunE:    runOncallOverride,
+}
 
-		userID := args[0]
-		// validate flags
-		if flagOncallOverrideStart == "" {
-			return fmt.Errorf("start time not specified")
-		}
-		start, err := parseOverrideTimeString(flagOncallOverrideStart)
-		if err != nil {
-			return fmt.Errorf("failed to parse start time: %w", err)
-		}
-		if flagOncallOverrideEnd == "" {
-			return fmt.Errorf("end time not specified")
-		}
-		end, err := parseOverrideTimeString(flagOncallOverrideEnd)
-		if err != nil {
-			return fmt.Errorf("failed to parse end time: %w", err)
-		}
-		if !end.After(*start) {
-			return fmt.Errorf("end time must be after start time")
-		}
-		fmt.Printf("Creating override for user %q from %s to %s (duration: %s)\n", userID, start, end, end.Sub(*start))
+// runOncallOverride creates an override for the user in args[0] on the
+// schedule in args[1] (or the default schedule), from --start-time to
+// --end-time. With --rrule, one override is created per occurrence of the
+// recurrence rule instead.
+func runOncallOverride(cmd *cobra.Command, args []string) error {
+	logrus.Debugf("Running override command")
+	ctx := context.Background()
+	cfg, err := GetConfig()
+	if err != nil {
+		return err
+	}
+	if flagOncallOverrideRRule != "" {
+		return runRecurringOncallOverride(ctx, cfg, args)
+	}
 
-		client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+	userID := args[0]
+	// validate flags
+	if flagOncallOverrideStart == "" {
+		return fmt.Errorf("start time not specified")
+	}
+	start, err := parseOverrideTimeString(flagOncallOverrideStart)
+	if err != nil {
+		return fmt.Errorf("failed to parse start time: %w", err)
+	}
+	if flagOncallOverrideEnd == "" {
+		return fmt.Errorf("end time not specified")
+	}
+	end, err := parseOverrideTimeString(flagOncallOverrideEnd)
+	if err != nil {
+		return fmt.Errorf("failed to parse end time: %w", err)
+	}
+	if !end.After(*start) {
+		return fmt.Errorf("end time must be after start time")
+	}
+	fmt.Printf("Creating override for user %q from %s to %s (duration: %s)\n", userID, start, end, end.Sub(*start))
 
-		scheduleID := cfg.Oncall.DefaultSchedule
-		if len(args) > 1 {
-			scheduleID = args[1]
-		}
-		if scheduleID == "" {
-			return fmt.Errorf("no schedule ID specified")
-		}
+	client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
 
-		// search for the specified user
-		uopts := pagerduty.ListUsersOptions{
-			Query:    userID,
-			Includes: []string{"contact_methods"},
-		}
-		resp, err := client.ListUsersWithContext(ctx, uopts)
-		if err != nil {
-			return fmt.Errorf("failed to find users matching %q: %w", userID, err)
-		}
-		if len(resp.Users) == 0 {
-			return fmt.Errorf("no user found matching %q", userID)
-		}
-		if len(resp.Users) > 1 {
-			fmt.Printf("Found more than one user matching %q, try using a narrower search\n", userID)
-			for idx, u := range resp.Users {
-				fmt.Printf("%d)  %s <%s> %s\n", idx+1, u.Name, u.Email, u.JobTitle)
-			}
-			os.Exit(1)
-		}
-		user := resp.Users[0]
-
-		// search for a schedule
-		log.Printf("Searching schedules matching %q", scheduleID)
-		sopts := pagerduty.GetScheduleOptions{
-			Since:    start.Format(time.RFC3339),
-			Until:    end.Format(time.RFC3339),
-			TimeZone: cfg.Timezone,
+	scheduleID := cfg.Oncall.DefaultSchedule
+	if len(args) > 1 {
+		scheduleID = args[1]
+	}
+	if scheduleID == "" {
+		return fmt.Errorf("no schedule ID specified")
+	}
+
+	scheduleID, err = resolveScheduleID(ctx, client, scheduleID)
+	if err != nil {
+		return err
+	}
+
+	// search for the specified user
+	user, err := lookupUser(ctx, client, userID, true)
+	if err != nil {
+		return err
+	}
+
+	// search for a schedule. Render it well beyond the override window, so
+	// that the shifts it cuts into are shown in full.
+	log.Printf("Searching schedules matching %q", scheduleID)
+	sched, segments, err := fetchSchedule(ctx, client, scheduleID, start.Add(-shiftSearchWindow), end.Add(shiftSearchWindow), cfg.Timezone)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", ansi.Bold(sched.Name))
+	fmt.Printf("    Summary: %s\n", ansi.ToURL(sched.Summary, sched.HTMLURL))
+	fmt.Printf("    Description: %s\n", sched.Description)
+	fmt.Println()
+	override := scheduleSegment{Start: *start, End: *end, User: user.APIObject}
+	before := overlappingSegments(segments, *start, *end)
+	printOverridePreview(before, override)
+
+	// check that this user isn't already oncall at that time
+	if len(before) == 1 && before[0].User.ID == user.ID && !before[0].Start.After(*start) && !before[0].End.Before(*end) {
+		str := ansi.Bold(fmt.Sprintf("The user %s <%s> is already oncall at that time, aborting\n", user.Name, user.Email))
+		fmt.Print(str)
+		os.Exit(1)
+	}
+	checks, err := checkOverride(ctx, client, &cfg.Oncall.OverrideChecks, user, scheduleID, *start, *end)
+	if err != nil {
+		return err
+	}
+	if hard := printOverrideChecks(checks); hard > 0 && !flagOncallOverrideForce {
+		if flagOncallOverrideDryRun {
+			fmt.Printf("Dry run, override not created. It would need --force because of %d failed checks\n", hard)
+			return nil
 		}
-		sched, err := client.GetScheduleWithContext(ctx, scheduleID, sopts)
+		return fmt.Errorf("%d safety checks failed, use --force to create the override anyway", hard)
+	}
+	if flagOncallOverrideDryRun {
+		fmt.Printf("Dry run, override not created\n")
+		return nil
+	}
+
+	//
+	if !flagOncallOverrideYes {
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Printf("Do you want to override the above schedule with the user %s <%s> %s ? [yN] ", user.Name, user.Email, user.JobTitle)
+		input, err := reader.ReadString('\n')
 		if err != nil {
-			return fmt.Errorf("failed to get schedule with ID %q: %w", scheduleID, err)
+			return fmt.Errorf("failed to read from stdin: %w", err)
 		}
-		currentOncalls := []pagerduty.APIObject{}
-		fmt.Printf("%s\n", ansi.Bold(sched.Name))
-		fmt.Printf("    Summary: %s\n", ansi.ToURL(sched.Summary, sched.HTMLURL))
-		fmt.Printf("    Description: %s\n", sched.Description)
-		fmt.Println()
-		fmt.Printf("%s:\n", ansi.Bold("Current oncall(s)"))
-		for _, entry := range sched.FinalSchedule.RenderedScheduleEntries {
-			start, err := time.Parse(time.RFC3339, entry.Start)
-			if err != nil {
-				log.Fatalf("Start time %q is not in RFC3339 format: %v", entry.Start, err)
-			}
-			end, err := time.Parse(time.RFC3339, entry.End)
-			if err != nil {
-				log.Fatalf("End time %q is not in RFC3339 format: %v", entry.End, err)
-			}
-			timeFmt := "Mon 02 Jan 2006 15:04:05 MST"
-			fmt.Printf("    %s - %s (%s)\t%+v\n",
-				start.Format(timeFmt),
-				end.Format(timeFmt),
-				end.Sub(start),
-				ansi.ToURL(entry.User.Summary, entry.User.HTMLURL),
-			)
-			currentOncalls = append(currentOncalls, entry.User)
+		input = strings.TrimSpace(strings.ToLower(input))
+		if input != "y" {
+			fmt.Printf("\nAborting\n")
+			os.Exit(0)
 		}
-		fmt.Println()
+	}
 
-		// check that this user isn't already oncall at that time
-		if len(currentOncalls) == 1 && currentOncalls[0].ID == user.ID {
-			str := ansi.Bold(fmt.Sprintf("The user %s <%s> is already oncall at that time, aborting\n", user.Name, user.Email))
-			fmt.Print(str)
-			os.Exit(1)
-		}
+	// create the override
+	fmt.Printf("Overriding schedule with user %s <%s>\n", user.Name, user.Email)
 
-		//
-		if !flagOncallOverrideYes {
-			reader := bufio.NewReader(os.Stdin)
-			fmt.Printf("Do you want to override the above schedule with the user %s <%s> %s ? [yN] ", user.Name, user.Email, user.JobTitle)
-			input, err := reader.ReadString('\n')
-			if err != nil {
-				return fmt.Errorf("failed to read from stdin: %w", err)
-			}
-			input = strings.TrimSpace(strings.ToLower(input))
-			if input != "y" {
-				fmt.Printf("\nAborting\n")
-				os.Exit(0)
-			}
-		}
+	_, err = client.CreateOverrideWithContext(ctx, scheduleID, pagerduty.Override{
+		User:  user.APIObject,
+		Start: start.Format(time.RFC3339),
+		End:   end.Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("override creation failed: %w", err)
+	}
+	fmt.Printf("Override created\n")
 
-		// create the override
-		fmt.Printf("Overriding schedule with user %s <%s>\n", user.Name, user.Email)
+	return nil
+}
 
-		override := pagerduty.Override{
-			User:  user.APIObject,
-			Start: start.Format(time.RFC3339),
-			End:   end.Format(time.RFC3339),
+// printOverridePreview prints the final schedule before and after applying
+// the override. Shifts that go away are printed in red, the new ones in green,
+// and a warning is printed for every shift that the override only partially
+// covers, since that's usually a mistake.
+func printOverridePreview(before []scheduleSegment, override scheduleSegment) {
+	after := overlaySegment(before, override)
+	fmt.Printf("%s:\n", ansi.Bold("Before"))
+	if len(before) == 0 {
+		fmt.Printf("    nobody is oncall\n")
+	}
+	for _, s := range before {
+		line := fmt.Sprintf("%s\t%s", s, s.User.Summary)
+		if !hasSegment(after, s) {
+			line = color.RedString("- " + line)
+		} else {
+			line = "  " + line
+		}
+		fmt.Printf("  %s\n", line)
+	}
+	fmt.Printf("%s:\n", ansi.Bold("After"))
+	for _, s := range after {
+		line := fmt.Sprintf("%s\t%s", s, s.User.Summary)
+		if !hasSegment(before, s) {
+			line = color.GreenString("+ " + line)
+		} else {
+			line = "  " + line
+		}
+		fmt.Printf("  %s\n", line)
+	}
+	fmt.Println()
+	for _, s := range before {
+		if s.User.ID == override.User.ID {
+			continue
 		}
-		_, err = client.CreateOverrideWithContext(ctx, scheduleID, override)
-		if err != nil {
-			return fmt.Errorf("override creation failed: %w", err)
+		if s.Start.Before(override.Start) || s.End.After(override.End) {
+			fmt.Printf("%s the override only covers part of the shift of %s (%s)\n", color.YellowString("Warning:"), s.User.Summary, s)
 		}
-		fmt.Printf("Override created\n")
-
-		return nil
-	},
+	}
 }
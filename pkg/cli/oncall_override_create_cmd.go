@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/ansi"
+	"github.com/insomniacslk/sre/pkg/config"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagOncallOverrideRRule string
+	flagOncallOverrideFrom  string
+	flagOncallOverrideUntil string
+)
+
+func init() {
+	OncallOverrideCmd.AddCommand(OncallOverrideCreateCmd)
+	OncallOverrideCreateCmd.Flags().StringVarP(&flagOncallOverrideRRule, "rrule", "r", "", `Recurrence rule (RFC 5545 subset), e.g. "FREQ=WEEKLY;BYDAY=TU". With a rule, --start and --end are times of the day (HH:MM) in the configured timezone`)
+	OncallOverrideCreateCmd.Flags().StringVar(&flagOncallOverrideFrom, "from", "", "First day (YYYY-MM-DD) of the recurrence (default: today)")
+	OncallOverrideCreateCmd.Flags().StringVarP(&flagOncallOverrideUntil, "until", "u", "", "Last day (YYYY-MM-DD, inclusive) of the recurrence")
+}
+
+var OncallOverrideCreateCmd = &cobra.Command{
+	Use:   "create <user> [schedule]",
+	Short: "Create an override, or a series of recurring overrides (PagerDuty)",
+	Long: `Create an override in the oncall schedule.
+
+Without --rrule this is the same as the ` + "`override`" + ` command. With --rrule, one
+override is created for each occurrence of the recurrence rule, e.g. every
+Tuesday from 09:00 to 17:00 until March 2027:
+
+  sre oncall override create alice --rrule "FREQ=WEEKLY;BYDAY=TU" --start 09:00 --end 17:00 --until 2027-03-01
+
+Occurrences are expanded in the configured timezone and keep their wall clock
+times across DST changes. If --end is not after --start, each occurrence ends
+on the following day. The safety checks of the ` + "`override`" + ` command run on each
+occurrence, and failures need --force.
+
+The recurrence starts (DTSTART) now, or at the beginning of the --from day,
+and COUNT counts the occurrences from there: without --from, COUNT=4 creates
+4 overrides. Occurrences that already started are skipped.
+
+Supported rule parts: FREQ (DAILY, WEEKLY, MONTHLY), INTERVAL, BYDAY,
+BYMONTHDAY, COUNT and UNTIL. The recurrence must be bounded by COUNT, UNTIL or
+--until.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runOncallOverride,
+}
+
+// runRecurringOncallOverride creates one override per occurrence of
+// --rrule, for the user in args[0] on the schedule in args[1] (or the default
+// schedule).
+func runRecurringOncallOverride(ctx context.Context, cfg *config.Config, args []string) error {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return fmt.Errorf("cannot load timezone %q: %w", cfg.Timezone, err)
+	}
+	rule, err := parseRecurrenceRule(flagOncallOverrideRRule, loc)
+	if err != nil {
+		return fmt.Errorf("invalid recurrence rule: %w", err)
+	}
+	if flagOncallOverrideStart == "" || flagOncallOverrideEnd == "" {
+		return fmt.Errorf("both --start and --end must be specified")
+	}
+	startClock, err := parseClockTime(flagOncallOverrideStart)
+	if err != nil {
+		return fmt.Errorf("failed to parse start time: %w", err)
+	}
+	endClock, err := parseClockTime(flagOncallOverrideEnd)
+	if err != nil {
+		return fmt.Errorf("failed to parse end time: %w", err)
+	}
+	now := time.Now()
+	from := now
+	if flagOncallOverrideFrom != "" {
+		from, err = time.ParseInLocation(time.DateOnly, flagOncallOverrideFrom, loc)
+		if err != nil {
+			return fmt.Errorf("failed to parse --from: %w", err)
+		}
+	}
+	var until time.Time
+	if flagOncallOverrideUntil != "" {
+		until, err = time.ParseInLocation(time.DateOnly, flagOncallOverrideUntil, loc)
+		if err != nil {
+			return fmt.Errorf("failed to parse --until: %w", err)
+		}
+	}
+	occurrences, err := rule.Expand(from, startClock, endClock, until, loc)
+	if err != nil {
+		return err
+	}
+	// skip the occurrences that already started
+	upcoming := occurrences[:0]
+	for _, o := range occurrences {
+		if o.Start.After(now) {
+			upcoming = append(upcoming, o)
+		}
+	}
+	if len(upcoming) == 0 {
+		fmt.Printf("The recurrence has no upcoming occurrences, nothing to do\n")
+		return nil
+	}
+
+	scheduleID := cfg.Oncall.DefaultSchedule
+	if len(args) > 1 {
+		scheduleID = args[1]
+	}
+	if scheduleID == "" {
+		return fmt.Errorf("no schedule ID specified")
+	}
+	client := pagerduty.NewClient(cfg.PagerDuty.UserToken, withRateLimitRetries())
+	scheduleID, err = resolveScheduleID(ctx, client, scheduleID)
+	if err != nil {
+		return err
+	}
+	user, err := lookupUser(ctx, client, args[0], true)
+	if err != nil {
+		return err
+	}
+	sched, err := client.GetScheduleWithContext(ctx, scheduleID, pagerduty.GetScheduleOptions{TimeZone: cfg.Timezone})
+	if err != nil {
+		return fmt.Errorf("failed to get schedule with ID %q: %w", scheduleID, err)
+	}
+	fmt.Printf("%s\n", ansi.Bold(sched.Name))
+	fmt.Printf("    Summary: %s\n", ansi.ToURL(sched.Summary, sched.HTMLURL))
+	fmt.Println()
+	fmt.Printf("%s:\n", ansi.Bold(fmt.Sprintf("Overrides for %s <%s>", user.Name, user.Email)))
+	for _, o := range upcoming {
+		fmt.Printf("    %s - %s (%s)\n", o.Start.Format(shiftTimeFormat), o.End.Format(shiftTimeFormat), o.End.Sub(o.Start))
+	}
+	fmt.Println()
+	overrides := make([]checkedOverride, 0, len(upcoming))
+	for _, o := range upcoming {
+		overrides = append(overrides, checkedOverride{
+			Label:      fmt.Sprintf("Override %s - %s", o.Start.Format(shiftTimeFormat), o.End.Format(shiftTimeFormat)),
+			User:       user,
+			ScheduleID: scheduleID,
+			Start:      o.Start,
+			End:        o.End,
+		})
+	}
+	hard, err := checkOverrides(ctx, client, &cfg.Oncall.OverrideChecks, overrides)
+	if err != nil {
+		return err
+	}
+	if hard > 0 && !flagOncallOverrideForce {
+		if flagOncallOverrideDryRun {
+			fmt.Printf("Dry run, %d overrides not created. They would need --force because of %d failed checks\n", len(upcoming), hard)
+			return nil
+		}
+		return fmt.Errorf("%d safety checks failed, use --force to create the overrides anyway", hard)
+	}
+	if flagOncallOverrideDryRun {
+		fmt.Printf("Dry run, %d overrides not created\n", len(upcoming))
+		return nil
+	}
+	if !flagOncallOverrideYes {
+		ok, err := confirm(fmt.Sprintf("Do you want to create the above %d overrides with the user %s <%s> %s ?", len(upcoming), user.Name, user.Email, user.JobTitle))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Printf("\nAborting\n")
+			return nil
+		}
+	}
+
+	var failed int
+	for _, o := range upcoming {
+		override := pagerduty.Override{
+			User:  user.APIObject,
+			Start: o.Start.Format(time.RFC3339),
+			End:   o.End.Format(time.RFC3339),
+		}
+		if _, err := client.CreateOverrideWithContext(ctx, scheduleID, override); err != nil {
+			fmt.Printf("Override %s - %s failed: %v\n", o.Start.Format(shiftTimeFormat), o.End.Format(shiftTimeFormat), err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d overrides could not be created", failed, len(upcoming))
+	}
+	fmt.Printf("%d overrides created\n", len(upcoming))
+	return nil
+}
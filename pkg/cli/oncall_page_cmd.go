@@ -0,0 +1,277 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/ansi"
+	"github.com/insomniacslk/sre/pkg/config"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagOncallPageUrgency string
+	flagOncallPageDetails string
+	flagOncallPageYes     bool
+)
+
+func init() {
+	OncallCmd.AddCommand(OncallPageCmd)
+	OncallPageCmd.Flags().StringVarP(&flagOncallPageUrgency, "urgency", "u", "high", "Urgency of the incident, high or low")
+	OncallPageCmd.Flags().StringVarP(&flagOncallPageDetails, "details", "d", "", "Details of the incident, shown in its body")
+	OncallPageCmd.Flags().BoolVarP(&flagOncallPageYes, "yes", "y", false, "Do not ask for confirmation")
+}
+
+var OncallPageCmd = &cobra.Command{
+	Use:   "page <component> <message>",
+	Short: "Page the oncall of a shortlist component by creating an incident (PagerDuty)",
+	Long: `Page the oncall of a component of ` + "`oncall.shortlist`" + `, by creating an incident
+titled <message>. The component is matched like the filter of the ` + "`shortlist`" + `
+command. With --yes, it must match exactly one entry by component, name, alias or
+synonym, and close matches are refused.
+
+The incident is created on the entry's service (or its ` + "`page_service_id`" + `, or the
+service using its escalation policy or schedules). With ` + "`page_via: escalation_policy`" + `,
+the default for entries with an ` + "`escalation_policy_id`" + `, the incident is also
+assigned to the entry's escalation policy.
+
+Who will be notified, level by level, is shown before asking for confirmation.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logrus.Debugf("Running oncall page command")
+		ctx := context.Background()
+		cfg, err := GetConfig()
+		if err != nil {
+			return err
+		}
+		if flagOncallPageUrgency != "high" && flagOncallPageUrgency != "low" {
+			return fmt.Errorf("invalid urgency %q, must be high or low", flagOncallPageUrgency)
+		}
+		component, message := strings.TrimSpace(args[0]), strings.TrimSpace(args[1])
+		if message == "" {
+			return fmt.Errorf("the message cannot be empty")
+		}
+		entries := cfg.Oncall.Shortlist
+		if len(entries) == 0 {
+			return fmt.Errorf("no shortlist configured; add entries under `oncall.shortlist` (see the `config-example` subcommand)")
+		}
+		selected, err := pageShortlistEntries(entries, component, cfg.Oncall.Synonyms, flagOncallPageYes)
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(selected))
+		for _, e := range selected {
+			names = append(names, e.Name)
+		}
+		idx, err := pick(fmt.Sprintf("shortlist entries matching %q", component), names)
+		if err != nil {
+			return err
+		}
+		e := selected[idx]
+
+		client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+		target, err := resolvePageTarget(ctx, client, cfg, e)
+		if err != nil {
+			return err
+		}
+		g, err := buildEscalationPolicyGraph(ctx, client, *target.Notified, time.Now())
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf(ansi.Bold("Component :")+" %s\n", e.Name)
+		fmt.Printf(ansi.Bold("Service   :")+" %s [ID: %s]\n", ansi.ToURL(target.Service.Name, target.Service.HTMLURL), target.Service.ID)
+		fmt.Printf(ansi.Bold("Policy    :")+" %s [ID: %s]\n", ansi.ToURL(target.Notified.Name, target.Notified.HTMLURL), target.Notified.ID)
+		fmt.Printf(ansi.Bold("Title     :")+" %s\n", message)
+		fmt.Printf(ansi.Bold("Urgency   :")+" %s\n", flagOncallPageUrgency)
+		if flagOncallPageDetails != "" {
+			fmt.Printf(ansi.Bold("Details   :")+" %s\n", flagOncallPageDetails)
+		}
+		fmt.Print(ansi.Bold("Notifies  :\n"))
+		for _, line := range notificationPlan(g) {
+			fmt.Printf("  %s\n", line)
+		}
+		if !flagOncallPageYes {
+			ok, err := confirm(fmt.Sprintf("Do you want to page %s?", e.Name))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Printf("\nAborting\n")
+				return nil
+			}
+		}
+
+		me, err := client.GetCurrentUserWithContext(ctx, pagerduty.GetCurrentUserOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get the current user: %w", err)
+		}
+		opts := pagerduty.CreateIncidentOptions{
+			Title:   message,
+			Service: &pagerduty.APIReference{ID: target.Service.ID, Type: "service_reference"},
+			Urgency: flagOncallPageUrgency,
+		}
+		if target.Assigned {
+			opts.EscalationPolicy = &pagerduty.APIReference{ID: target.Notified.ID, Type: "escalation_policy_reference"}
+		}
+		if flagOncallPageDetails != "" {
+			opts.Body = &pagerduty.APIDetails{Type: "incident_body", Details: flagOncallPageDetails}
+		}
+		incident, err := client.CreateIncidentWithContext(ctx, me.Email, &opts)
+		if err != nil {
+			return fmt.Errorf("failed to create incident: %w", err)
+		}
+		fmt.Printf("Incident #%d created: %s\n", incident.IncidentNumber, ansi.ToURL(incident.Title, incident.HTMLURL))
+		return nil
+	},
+}
+
+// pageTarget is where the incident paging a shortlist entry is created.
+type pageTarget struct {
+	Service *pagerduty.Service
+	// Notified is the escalation policy notified by the incident.
+	Notified *pagerduty.EscalationPolicy
+	// Assigned is set when the incident is explicitly assigned to Notified
+	// instead of following the service's escalation policy.
+	Assigned bool
+}
+
+// resolvePageTarget resolves the service to create the incident on and the
+// escalation policy it notifies, according to the entry's page_via and
+// page_service_id.
+func resolvePageTarget(ctx context.Context, client *pagerduty.Client, cfg *config.Config, e config.OncallShortlistEntry) (*pageTarget, error) {
+	var (
+		ep      *pagerduty.EscalationPolicy
+		service *pagerduty.Service
+		err     error
+	)
+	if e.HasEscalationPolicy() {
+		ep, service, err = shortlistEscalationPolicy(ctx, client, e)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if e.PageServiceID != "" {
+		service, err = client.GetServiceWithContext(ctx, e.PageServiceID, &pagerduty.GetServiceOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get service with ID %q: %w", e.PageServiceID, err)
+		}
+	}
+	if service == nil {
+		service, err = pickPageService(ctx, client, cfg, e, ep)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if e.PageTarget() == config.PageViaEscalationPolicy && ep != nil {
+		return &pageTarget{Service: service, Notified: ep, Assigned: ep.ID != service.EscalationPolicy.ID}, nil
+	}
+	notified, err := lookupEscalationPolicy(ctx, client, service.EscalationPolicy.ID, true)
+	if err != nil {
+		return nil, err
+	}
+	return &pageTarget{Service: service, Notified: notified}, nil
+}
+
+// pickPageService returns the service using the entry's escalation policy
+// (or, without one, the policies including its schedules), asking the user
+// to pick one if there are several.
+func pickPageService(ctx context.Context, client *pagerduty.Client, cfg *config.Config, e config.OncallShortlistEntry, ep *pagerduty.EscalationPolicy) (*pagerduty.Service, error) {
+	var candidates []pagerduty.APIObject
+	if ep != nil {
+		candidates = ep.Services
+	} else {
+		var teamIDs []string
+		if len(cfg.PagerDuty.Teams) > 0 {
+			var err error
+			teamIDs, err = resolveTeamIDs(ctx, client, cfg.PagerDuty.Teams)
+			if err != nil {
+				return nil, err
+			}
+		}
+		services, err := shortlistServices(ctx, client, teamIDs, e)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range services {
+			candidates = append(candidates, serviceReference(s))
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no service found to page %q, set its `page_service_id`", e.Name)
+	}
+	names := make([]string, 0, len(candidates))
+	for _, s := range candidates {
+		names = append(names, fmt.Sprintf("%s [ID: %s]", s.Summary, s.ID))
+	}
+	idx, err := pick(fmt.Sprintf("services to page %q", e.Name), names)
+	if err != nil {
+		return nil, err
+	}
+	service, err := client.GetServiceWithContext(ctx, candidates[idx].ID, &pagerduty.GetServiceOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service with ID %q: %w", candidates[idx].ID, err)
+	}
+	return service, nil
+}
+
+// notificationPlan describes who an incident notifies, level by level, and
+// when it escalates.
+func notificationPlan(g *escalationPolicyGraph) []string {
+	var lines []string
+	elapsed := uint(0)
+	for _, l := range g.Levels {
+		when := "immediately"
+		if l.Number > 1 {
+			when = fmt.Sprintf("after %d min if not acknowledged", elapsed)
+		}
+		var names []string
+		for _, oc := range levelOncalls(l) {
+			name := oc.User.Name
+			if name == "" {
+				name = oc.User.Summary
+			}
+			if oc.Schedule != "" {
+				name += " via " + oc.Schedule
+			}
+			names = append(names, name)
+		}
+		who := "nobody oncall"
+		if len(names) > 0 {
+			who = strings.Join(names, ", ")
+		}
+		lines = append(lines, fmt.Sprintf("level %d, %s: %s", l.Number, when, who))
+		elapsed += l.Delay
+	}
+	if g.Policy.NumLoops > 0 {
+		lines = append(lines, fmt.Sprintf("then %s", g.repeatDescription()))
+	}
+	return lines
+}
+
+// pageShortlistEntries returns the shortlist entries the component may refer
+// to. A single whole-term match (by component, name, alias or synonym) wins
+// over the fuzzy ones. Without confirmation only that is accepted, so that a
+// typo never pages a component that was not named.
+func pageShortlistEntries(entries []config.OncallShortlistEntry, component string, synonyms [][]string, noConfirm bool) ([]config.OncallShortlistEntry, error) {
+	selected := selectShortlistEntries(entries, component, synonyms, true, false)
+	if len(selected) == 1 {
+		return selected, nil
+	}
+	if noConfirm {
+		if len(selected) > 1 {
+			return nil, fmt.Errorf("%d shortlist entries match %q, --yes requires exactly one", len(selected), component)
+		}
+		return nil, fmt.Errorf("no shortlist entry matches %q exactly, which --yes requires%s", component, didYouMean(entries, component, false))
+	}
+	selected = selectShortlistEntries(entries, component, synonyms, false, false)
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no shortlist entries match %q%s", component, didYouMean(entries, component, false))
+	}
+	return selected, nil
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/config"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestPageShortlistEntries(t *testing.T) {
+	entries := []config.OncallShortlistEntry{
+		{Name: "Storage", Component: "storage", Aliases: []string{"disk"}},
+		{Name: "Storage backups", Component: "backups"},
+		{Name: "Kubernetes", Component: "kubernetes"},
+	}
+	syn := [][]string{{"k8s", "kubernetes"}}
+	tests := []struct {
+		name      string
+		component string
+		noConfirm bool
+		want      []string
+		wantErr   bool
+	}{
+		{name: "exact", component: "storage", noConfirm: true, want: []string{"Storage"}},
+		{name: "alias", component: "disk", noConfirm: true, want: []string{"Storage"}},
+		{name: "synonym", component: "k8s", noConfirm: true, want: []string{"Kubernetes"}},
+		{name: "typo is refused without confirmation", component: "stroage", noConfirm: true, wantErr: true},
+		{name: "typo is matched with confirmation", component: "stroage", want: []string{"Storage", "Storage backups"}},
+		{name: "partial match with confirmation", component: "stor", want: []string{"Storage", "Storage backups"}},
+		{name: "partial match is refused without confirmation", component: "stor", noConfirm: true, wantErr: true},
+		{name: "no match", component: "network", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pageShortlistEntries(entries, tc.component, syn, tc.noConfirm)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			var names []string
+			for _, e := range got {
+				names = append(names, e.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("got %v, want %v", names, tc.want)
+			}
+		})
+	}
+}
+
+func TestNotificationPlan(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	ep := pagerduty.EscalationPolicy{
+		APIObject: pagerduty.APIObject{ID: "EP1"},
+		Name:      "Storage",
+		NumLoops:  1,
+		EscalationRules: []pagerduty.EscalationRule{
+			{Delay: 15, Targets: []pagerduty.APIObject{{ID: "S1", Type: "schedule_reference", Summary: "Storage Primary"}}},
+			{Delay: 30, Targets: []pagerduty.APIObject{{ID: "S2", Type: "schedule_reference", Summary: "Storage Secondary"}}},
+			{Delay: 30, Targets: []pagerduty.APIObject{{ID: "U3", Type: "user_reference", Summary: "Carol"}}},
+		},
+	}
+	oncalls := []pagerduty.OnCall{
+		{EscalationLevel: 1, Schedule: pagerduty.Schedule{APIObject: pagerduty.APIObject{ID: "S1"}}, User: pagerduty.User{APIObject: pagerduty.APIObject{ID: "U1"}, Name: "Alice"}},
+	}
+	got := notificationPlan(newEscalationPolicyGraph(ep, oncalls, now))
+	want := []string{
+		"level 1, immediately: Alice via Storage Primary",
+		"level 2, after 15 min if not acknowledged: nobody oncall",
+		"level 3, after 45 min if not acknowledged: Carol",
+		"then repeats once",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,349 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/config"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagOncallPhoneTreeFormat string
+	flagOncallPhoneTreeOutput string
+)
+
+func init() {
+	OncallCmd.AddCommand(OncallPhoneTreeCmd)
+	OncallPhoneTreeCmd.Flags().StringVarP(&flagOncallPhoneTreeFormat, "format", "F", "markdown", "Output format: markdown or html")
+	OncallPhoneTreeCmd.Flags().StringVarP(&flagOncallPhoneTreeOutput, "output", "o", "", "File to write the phone tree to (default: standard output)")
+}
+
+var OncallPhoneTreeCmd = &cobra.Command{
+	Use:   "phonetree [filter]",
+	Short: "Print an escalation phone tree for the shortlist components (PagerDuty)",
+	Long: `Print an escalation phone tree to use when PagerDuty itself is degraded.
+
+For each component of ` + "`oncall.shortlist`" + ` (optionally filtered like the
+` + "`shortlist`" + ` command), and each escalation policy paging it, the tree lists
+the primary (oncall at escalation level 1) and the secondary (oncall at
+escalation level 2), with the phone numbers of their phone and SMS contact
+methods and the time range of their shifts.
+
+The escalation policy of an entry is the one it references, directly or
+through its service. Entries referencing only schedules use the escalation
+policies targeting those schedules.
+
+The output is Markdown or a standalone HTML page, meant to be printed or
+stored somewhere that does not depend on PagerDuty:
+
+  sre oncall phonetree --format html -o phonetree.html`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logrus.Debugf("Running oncall phonetree command")
+		ctx := context.Background()
+		cfg, err := GetConfig()
+		if err != nil {
+			return err
+		}
+		var render func(io.Writer, *phoneTree) error
+		switch flagOncallPhoneTreeFormat {
+		case "markdown", "md":
+			render = renderPhoneTreeMarkdown
+		case "html":
+			render = renderPhoneTreeHTML
+		default:
+			return fmt.Errorf("unknown format %q, must be one of markdown, html", flagOncallPhoneTreeFormat)
+		}
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return fmt.Errorf("cannot load timezone %q: %w", cfg.Timezone, err)
+		}
+		if len(cfg.Oncall.Shortlist) == 0 {
+			return fmt.Errorf("no shortlist configured; add entries under `oncall.shortlist` (see the `config-example` subcommand)")
+		}
+		filter := strings.TrimSpace(strings.Join(args, " "))
+		selected := selectShortlistEntries(cfg.Oncall.Shortlist, filter, cfg.Oncall.Synonyms, false, false)
+		if len(selected) == 0 {
+			return fmt.Errorf("no shortlist entries match %q%s", filter, didYouMean(cfg.Oncall.Shortlist, filter, false))
+		}
+
+		client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+		now := time.Now().In(loc)
+		tree := phoneTree{Generated: now}
+		phones := make(map[string][]string)
+		userPhones := func(userID string) ([]string, error) {
+			if p, ok := phones[userID]; ok {
+				return p, nil
+			}
+			u, err := client.GetUserWithContext(ctx, userID, pagerduty.GetUserOptions{Includes: []string{"contact_methods"}})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get user %q: %w", userID, err)
+			}
+			var p []string
+			for _, c := range u.ContactMethods {
+				switch contactMethodType(c) {
+				case "phone_contact_method", "sms_contact_method":
+					if number := contactMethodPhone(c); !containsPhone(p, number) {
+						p = append(p, fmt.Sprintf("%s (%s)", number, c.Label))
+					}
+				}
+			}
+			phones[userID] = p
+			return p, nil
+		}
+		for _, e := range selected {
+			policyIDs, err := phoneTreePolicyIDs(ctx, client, e, now)
+			if err != nil {
+				return err
+			}
+			component := phoneTreeComponent{Name: e.Name}
+			if len(policyIDs) > 0 {
+				oncalls, err := listOnCalls(ctx, client, pagerduty.ListOnCallOptions{
+					EscalationPolicyIDs: policyIDs,
+					Since:               now.Format(time.RFC3339),
+					Until:               now.Add(time.Minute).Format(time.RFC3339),
+				})
+				if err != nil {
+					return fmt.Errorf("failed to get oncalls for %q: %w", e.Name, err)
+				}
+				component.Policies = phoneTreePolicies(oncalls, now)
+			}
+			for pidx := range component.Policies {
+				for sidx := range component.Policies[pidx].Shifts {
+					sh := &component.Policies[pidx].Shifts[sidx]
+					if sh.Phones, err = userPhones(sh.userID); err != nil {
+						return err
+					}
+					sh.Start, sh.End = sh.Start.In(loc), sh.End.In(loc)
+				}
+			}
+			tree.Components = append(tree.Components, component)
+		}
+
+		return writeOutput(flagOncallPhoneTreeOutput, func(w io.Writer) error {
+			return render(w, &tree)
+		})
+	},
+}
+
+// phoneTreePolicyIDs returns the IDs of the escalation policies paging a
+// shortlist entry: the one it references, directly or through its service,
+// or else the ones targeting its schedules.
+func phoneTreePolicyIDs(ctx context.Context, client *pagerduty.Client, e config.OncallShortlistEntry, at time.Time) ([]string, error) {
+	if e.HasEscalationPolicy() {
+		ep, _, err := shortlistEscalationPolicy(ctx, client, e)
+		if err != nil {
+			return nil, err
+		}
+		return []string{ep.ID}, nil
+	}
+	scheduleIDs, err := shortlistScheduleIDs(ctx, client, e)
+	if err != nil || len(scheduleIDs) == 0 {
+		return nil, err
+	}
+	oncalls, err := listOnCalls(ctx, client, pagerduty.ListOnCallOptions{
+		ScheduleIDs: scheduleIDs,
+		Since:       at.Format(time.RFC3339),
+		Until:       at.Add(time.Minute).Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oncalls for %q: %w", e.Name, err)
+	}
+	var ids []string
+	seen := make(map[string]struct{})
+	for _, oc := range oncalls {
+		if _, ok := seen[oc.EscalationPolicy.ID]; ok || oc.EscalationPolicy.ID == "" {
+			continue
+		}
+		seen[oc.EscalationPolicy.ID] = struct{}{}
+		ids = append(ids, oc.EscalationPolicy.ID)
+	}
+	return ids, nil
+}
+
+// containsPhone reports whether any of the formatted phone entries is for
+// the given number.
+func containsPhone(entries []string, number string) bool {
+	for _, e := range entries {
+		if strings.HasPrefix(e, number+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// phoneTree is an escalation phone tree: for each component, and each
+// escalation policy paging it, who is oncall at the first two levels.
+type phoneTree struct {
+	Generated  time.Time
+	Components []phoneTreeComponent
+}
+
+type phoneTreeComponent struct {
+	Name     string
+	Policies []phoneTreePolicy
+}
+
+type phoneTreePolicy struct {
+	Name   string
+	URL    string
+	Shifts []phoneTreeShift
+}
+
+// phoneTreeShift is somebody oncall at an escalation level. Start and End are
+// zero for users targeted directly by the policy, who are always oncall.
+type phoneTreeShift struct {
+	Role     string
+	Name     string
+	Schedule string
+	Phones   []string
+	Start    time.Time
+	End      time.Time
+
+	level  uint
+	userID string
+}
+
+// phoneTreeRoles maps the escalation levels in the phone tree to their role.
+var phoneTreeRoles = map[uint]string{1: "Primary", 2: "Secondary"}
+
+// phoneTreePolicies groups the oncall entries active at the given time by
+// escalation policy, keeping the primary (level 1) and secondary (level 2)
+// oncalls, once per user and level.
+func phoneTreePolicies(oncalls []pagerduty.OnCall, at time.Time) []phoneTreePolicy {
+	var policies []phoneTreePolicy
+	index := make(map[string]int)
+	seen := make(map[string]struct{})
+	for _, oc := range oncalls {
+		role, ok := phoneTreeRoles[oc.EscalationLevel]
+		if !ok || !onCallActiveAt(oc, at) {
+			continue
+		}
+		idx, ok := index[oc.EscalationPolicy.ID]
+		if !ok {
+			idx = len(policies)
+			index[oc.EscalationPolicy.ID] = idx
+			policies = append(policies, phoneTreePolicy{Name: oc.EscalationPolicy.Summary, URL: oc.EscalationPolicy.HTMLURL})
+		}
+		key := fmt.Sprintf("%s/%d/%s", oc.EscalationPolicy.ID, oc.EscalationLevel, oc.User.ID)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		sh := phoneTreeShift{Role: role, Name: oc.User.Summary, Schedule: oc.Schedule.Summary, level: oc.EscalationLevel, userID: oc.User.ID}
+		// unparseable or missing boundaries are left as zero, like for users
+		// targeted directly
+		sh.Start, _ = time.Parse(time.RFC3339, oc.Start)
+		sh.End, _ = time.Parse(time.RFC3339, oc.End)
+		policies[idx].Shifts = append(policies[idx].Shifts, sh)
+	}
+	for _, p := range policies {
+		sort.SliceStable(p.Shifts, func(i, j int) bool { return p.Shifts[i].level < p.Shifts[j].level })
+	}
+	return policies
+}
+
+// phoneTreeTime formats a shift boundary, which is zero for users that are
+// always oncall.
+func phoneTreeTime(t time.Time) string {
+	if t.IsZero() {
+		return "always"
+	}
+	return t.Format(shiftTimeFormat)
+}
+
+var markdownEscaper = strings.NewReplacer(`|`, `\|`, `*`, `\*`, `_`, `\_`)
+
+// renderPhoneTreeMarkdown renders the phone tree as Markdown.
+func renderPhoneTreeMarkdown(w io.Writer, tree *phoneTree) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Oncall phone tree\n\n")
+	fmt.Fprintf(&b, "Generated on %s. Call the primary (escalation level 1) first, then the secondary (escalation level 2) if the primary does not answer.\n", tree.Generated.Format(shiftTimeFormat))
+	for _, c := range tree.Components {
+		fmt.Fprintf(&b, "\n## %s\n", markdownEscaper.Replace(c.Name))
+		if len(c.Policies) == 0 {
+			fmt.Fprintf(&b, "\nNo escalation policies found.\n")
+		}
+		for _, s := range c.Policies {
+			if s.URL != "" {
+				fmt.Fprintf(&b, "\n### [%s](%s)\n\n", markdownEscaper.Replace(s.Name), s.URL)
+			} else {
+				fmt.Fprintf(&b, "\n### %s\n\n", markdownEscaper.Replace(s.Name))
+			}
+			if len(s.Shifts) == 0 {
+				fmt.Fprintf(&b, "Nobody oncall.\n")
+				continue
+			}
+			fmt.Fprintf(&b, "| Role | Name | Schedule | Phone | From | To |\n")
+			fmt.Fprintf(&b, "|------|------|----------|-------|------|----|\n")
+			for _, sh := range s.Shifts {
+				phones := "no phone number"
+				if len(sh.Phones) > 0 {
+					phones = strings.Join(sh.Phones, "<br>")
+				}
+				schedule := "-"
+				if sh.Schedule != "" {
+					schedule = markdownEscaper.Replace(sh.Schedule)
+				}
+				fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", sh.Role, markdownEscaper.Replace(sh.Name), schedule, markdownEscaper.Replace(phones),
+					phoneTreeTime(sh.Start), phoneTreeTime(sh.End))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var phoneTreeHTMLTemplate = template.Must(template.New("phonetree").Funcs(template.FuncMap{
+	"fmtTime": phoneTreeTime,
+	"tel":     func(p string) string { return strings.SplitN(p, " ", 2)[0] },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Oncall phone tree</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #999; padding: 4px 8px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>Oncall phone tree</h1>
+<p>Generated on {{fmtTime .Generated}}. Call the primary (escalation level 1) first, then the secondary (escalation level 2) if the primary does not answer.</p>
+{{- range .Components}}
+<h2>{{.Name}}</h2>
+{{- if not .Policies}}
+<p>No escalation policies found.</p>
+{{- end}}
+{{- range .Policies}}
+<h3>{{if .URL}}<a href="{{.URL}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</h3>
+{{- if .Shifts}}
+<table>
+<tr><th>Role</th><th>Name</th><th>Schedule</th><th>Phone</th><th>From</th><th>To</th></tr>
+{{- range .Shifts}}
+<tr><td>{{.Role}}</td><td>{{.Name}}</td><td>{{or .Schedule "-"}}</td><td>{{range $idx, $p := .Phones}}{{if $idx}}<br>{{end}}<a href="tel:{{tel $p}}">{{$p}}</a>{{else}}no phone number{{end}}</td><td>{{fmtTime .Start}}</td><td>{{fmtTime .End}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>Nobody oncall.</p>
+{{- end}}
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// renderPhoneTreeHTML renders the phone tree as a standalone HTML page.
+func renderPhoneTreeHTML(w io.Writer, tree *phoneTree) error {
+	return phoneTreeHTMLTemplate.Execute(w, tree)
+}
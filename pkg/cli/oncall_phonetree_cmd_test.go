@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestPhoneTreePolicies(t *testing.T) {
+	at := func(h int) string { return time.Date(2026, 10, 19, h, 0, 0, 0, time.UTC).Format(time.RFC3339) }
+	ep := func(id string) pagerduty.EscalationPolicy {
+		return pagerduty.EscalationPolicy{APIObject: pagerduty.APIObject{ID: id, Summary: "Policy " + id}}
+	}
+	oncall := func(policy string, level uint, userID, schedule, start, end string) pagerduty.OnCall {
+		return pagerduty.OnCall{
+			EscalationPolicy: ep(policy),
+			EscalationLevel:  level,
+			User:             pagerduty.User{APIObject: pagerduty.APIObject{ID: userID, Summary: "User " + userID}},
+			Schedule:         pagerduty.Schedule{APIObject: pagerduty.APIObject{Summary: schedule}},
+			Start:            start,
+			End:              end,
+		}
+	}
+	oncalls := []pagerduty.OnCall{
+		oncall("PEP1", 2, "PUSER02", "", "", ""),
+		oncall("PEP1", 1, "PUSER01", "Primary", at(6), at(18)),
+		oncall("PEP1", 1, "PUSER01", "Primary", at(6), at(18)),
+		oncall("PEP1", 1, "PUSER00", "Primary", at(0), at(6)),
+		oncall("PEP1", 3, "PUSER03", "Manager", at(0), at(24)),
+		oncall("PEP2", 1, "PUSER04", "Other", at(0), at(24)),
+	}
+	got := phoneTreePolicies(oncalls, time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC))
+	var lines []string
+	for _, p := range got {
+		lines = append(lines, p.Name)
+		for _, sh := range p.Shifts {
+			lines = append(lines, fmt.Sprintf("  %s %s (%s) %s - %s", sh.Role, sh.Name, sh.Schedule, phoneTreeTime(sh.Start), phoneTreeTime(sh.End)))
+		}
+	}
+	want := []string{
+		"Policy PEP1",
+		"  Primary User PUSER01 (Primary) Mon 19 Oct 2026 06:00 UTC - Mon 19 Oct 2026 18:00 UTC",
+		"  Secondary User PUSER02 () always - always",
+		"Policy PEP2",
+		"  Primary User PUSER04 (Other) Mon 19 Oct 2026 00:00 UTC - Tue 20 Oct 2026 00:00 UTC",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRenderPhoneTree(t *testing.T) {
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	tree := phoneTree{
+		Generated: start,
+		Components: []phoneTreeComponent{{
+			Name: "payments",
+			Policies: []phoneTreePolicy{{
+				Name: "Payments | policy",
+				Shifts: []phoneTreeShift{
+					{Role: "Primary", Name: "Alice", Schedule: "Payments | primary", Phones: []string{"+15555555 (Mobile)"}, Start: start, End: start.Add(24 * time.Hour)},
+					{Role: "Secondary", Name: "Bob"},
+				},
+			}},
+		}},
+	}
+	var md strings.Builder
+	if err := renderPhoneTreeMarkdown(&md, &tree); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"## payments\n",
+		`### Payments \| policy`,
+		`| Primary | Alice | Payments \| primary | +15555555 (Mobile) | Mon 19 Oct 2026 09:00 UTC | Tue 20 Oct 2026 09:00 UTC |`,
+		"| Secondary | Bob | - | no phone number | always | always |",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown does not contain %q:\n%s", want, md.String())
+		}
+	}
+	var html strings.Builder
+	if err := renderPhoneTreeHTML(&html, &tree); err != nil {
+		t.Fatal(err)
+	}
+	if want := `<a href="tel:&#43;15555555">&#43;15555555 (Mobile)</a>`; !strings.Contains(html.String(), want) {
+		t.Errorf("html does not contain %q:\n%s", want, html.String())
+	}
+}
@@ -42,6 +42,10 @@ var OncallScheduleCmd = &cobra.Command{
 		if scheduleID == "" {
 			logrus.Fatalf("No schedule ID specified")
 		}
+		scheduleID, err = resolveScheduleID(ctx, client, scheduleID)
+		if err != nil {
+			return err
+		}
 		log.Printf("Searching schedules matching %q", scheduleID)
 		// search for a schedule
 		now := time.Now()
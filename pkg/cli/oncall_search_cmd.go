@@ -12,8 +12,15 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// searchScheduleBatchSize is how many schedules are looked up with a single
+// ListOnCalls request.
+const searchScheduleBatchSize = 10
+
+var flagOncallSearchConcurrency int
+
 func init() {
 	OncallCmd.AddCommand(OncallSearchCmd)
+	OncallSearchCmd.Flags().IntVarP(&flagOncallSearchConcurrency, "concurrency", "j", defaultConcurrency, "Number of concurrent requests to get the oncalls of the matching schedules")
 }
 
 var OncallSearchCmd = &cobra.Command{
@@ -35,7 +42,7 @@ var OncallSearchCmd = &cobra.Command{
 		if len(args) > 0 {
 			query = strings.Join(args, " ")
 		}
-		client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+		client := pagerduty.NewClient(cfg.PagerDuty.UserToken, withRateLimitRetries())
 
 		// Resolve schedule name into ID(s)
 		sOpts := pagerduty.ListSchedulesOptions{
@@ -50,17 +57,27 @@ var OncallSearchCmd = &cobra.Command{
 			scheduleIDs = append(scheduleIDs, sc.ID)
 		}
 
-		opts := pagerduty.ListOnCallOptions{
-			ScheduleIDs: scheduleIDs,
-			Includes:    []string{"users"},
-			Until:       time.Now().Add(24 * time.Hour).Format(time.RFC3339),
-		}
-		resp, err := client.ListOnCallsWithContext(ctx, opts)
-		if err != nil {
-			logrus.Fatalf("Failed to get schedules: %v", err)
+		// get the oncalls of the schedules in batches, concurrently
+		until := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+		batches := batchStrings(scheduleIDs, searchScheduleBatchSize)
+		batchOncalls := make([][]pagerduty.OnCall, len(batches))
+		batchErrs := make([]error, len(batches))
+		forEachConcurrently(flagOncallSearchConcurrency, len(batches), func(idx int) {
+			batchOncalls[idx], batchErrs[idx] = listOnCalls(ctx, client, pagerduty.ListOnCallOptions{
+				ScheduleIDs: batches[idx],
+				Includes:    []string{"users"},
+				Until:       until,
+			})
+		})
+		var allOncalls []pagerduty.OnCall
+		for idx, err := range batchErrs {
+			if err != nil {
+				logrus.Fatalf("Failed to get schedules: %v", err)
+			}
+			allOncalls = append(allOncalls, batchOncalls[idx]...)
 		}
 		oncallBySchedule := make(map[string][]*pagerduty.OnCall)
-		for _, oc := range resp.OnCalls {
+		for _, oc := range allOncalls {
 			_, ok := oncallBySchedule[oc.Schedule.Summary]
 			if !ok {
 				oncallBySchedule[oc.Schedule.Summary] = []*pagerduty.OnCall{&oc}
@@ -94,3 +111,16 @@ var OncallSearchCmd = &cobra.Command{
 		return nil
 	},
 }
+
+// batchStrings splits items in batches of at most size items.
+func batchStrings(items []string, size int) [][]string {
+	var batches [][]string
+	for len(items) > size {
+		batches = append(batches, items[:size])
+		items = items[size:]
+	}
+	if len(items) > 0 {
+		batches = append(batches, items)
+	}
+	return batches
+}
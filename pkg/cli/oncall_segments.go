@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+// shiftSearchWindow is how far before and after a given time we render a
+// schedule when looking for the shift containing that time. PagerDuty clips
+// rendered entries to the requested range, so shifts longer than this are
+// reported as starting/ending at the window boundary.
+const shiftSearchWindow = 14 * 24 * time.Hour
+
+// shiftTimeFormat is the format used to print shift boundaries.
+const shiftTimeFormat = "Mon 02 Jan 2006 15:04 MST"
+
+// scheduleSegment is a parsed entry of a rendered PagerDuty schedule: who is
+// on call, and from when to when.
+type scheduleSegment struct {
+	Start time.Time
+	End   time.Time
+	User  pagerduty.APIObject
+}
+
+// Contains reports whether t falls within the segment. The start is inclusive
+// and the end is exclusive, like PagerDuty's handoffs.
+func (s scheduleSegment) Contains(t time.Time) bool {
+	return !t.Before(s.Start) && t.Before(s.End)
+}
+
+func (s scheduleSegment) String() string {
+	return fmt.Sprintf("%s - %s (%s)", s.Start.Format(shiftTimeFormat), s.End.Format(shiftTimeFormat), s.End.Sub(s.Start))
+}
+
+// renderedSegments parses the rendered entries of a schedule layer (usually
+// the final schedule) into segments.
+func renderedSegments(entries []pagerduty.RenderedScheduleEntry) ([]scheduleSegment, error) {
+	segments := make([]scheduleSegment, 0, len(entries))
+	for _, entry := range entries {
+		start, err := time.Parse(time.RFC3339, entry.Start)
+		if err != nil {
+			return nil, fmt.Errorf("start time %q is not in RFC3339 format: %w", entry.Start, err)
+		}
+		end, err := time.Parse(time.RFC3339, entry.End)
+		if err != nil {
+			return nil, fmt.Errorf("end time %q is not in RFC3339 format: %w", entry.End, err)
+		}
+		segments = append(segments, scheduleSegment{Start: start, End: end, User: entry.User})
+	}
+	return segments, nil
+}
+
+// segmentAt returns the segment containing t, or nil if nobody is on call at
+// that time.
+func segmentAt(segments []scheduleSegment, t time.Time) *scheduleSegment {
+	for idx := range segments {
+		if segments[idx].Contains(t) {
+			return &segments[idx]
+		}
+	}
+	return nil
+}
+
+// fetchSchedule gets a schedule with its final schedule rendered between since
+// and until, and parses the rendered entries into segments.
+func fetchSchedule(ctx context.Context, client *pagerduty.Client, scheduleID string, since, until time.Time, timezone string) (*pagerduty.Schedule, []scheduleSegment, error) {
+	opts := pagerduty.GetScheduleOptions{
+		Since:    since.Format(time.RFC3339),
+		Until:    until.Format(time.RFC3339),
+		TimeZone: timezone,
+	}
+	sched, err := client.GetScheduleWithContext(ctx, scheduleID, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get schedule with ID %q: %w", scheduleID, err)
+	}
+	segments, err := renderedSegments(sched.FinalSchedule.RenderedScheduleEntries)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid final schedule for %q: %w", sched.Name, err)
+	}
+	return sched, segments, nil
+}
+
+// overlappingSegments returns the segments that overlap the [start, end)
+// range, unclipped.
+func overlappingSegments(segments []scheduleSegment, start, end time.Time) []scheduleSegment {
+	var out []scheduleSegment
+	for _, s := range segments {
+		if s.End.After(start) && s.Start.Before(end) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// overlaySegment returns what segments look like once o is overridden on top
+// of them: segments partially covered by o are trimmed (or split in two), the
+// fully covered ones are dropped, and o is inserted in order.
+func overlaySegment(segments []scheduleSegment, o scheduleSegment) []scheduleSegment {
+	out := make([]scheduleSegment, 0, len(segments)+2)
+	inserted := false
+	for _, s := range segments {
+		if !inserted && !s.Start.Before(o.Start) {
+			out = append(out, o)
+			inserted = true
+		}
+		if !s.End.After(o.Start) || !s.Start.Before(o.End) {
+			// no overlap
+			out = append(out, s)
+			continue
+		}
+		if s.Start.Before(o.Start) {
+			head := s
+			head.End = o.Start
+			out = append(out, head)
+			if !inserted {
+				out = append(out, o)
+				inserted = true
+			}
+		}
+		if s.End.After(o.End) {
+			tail := s
+			tail.Start = o.End
+			out = append(out, tail)
+		}
+	}
+	if !inserted {
+		out = append(out, o)
+	}
+	return out
+}
+
+// hasSegment reports whether segments contain one with the same boundaries and
+// user as s.
+func hasSegment(segments []scheduleSegment, s scheduleSegment) bool {
+	for _, other := range segments {
+		if other.User.ID == s.User.ID && other.Start.Equal(s.Start) && other.End.Equal(s.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleGaps returns the intervals between since and until where none of
+// the segments has somebody on call. Segments must be sorted by start time.
+func scheduleGaps(segments []scheduleSegment, since, until time.Time) []timeRange {
+	var gaps []timeRange
+	covered := since
+	for _, s := range segments {
+		if !covered.Before(until) {
+			break
+		}
+		if s.Start.After(covered) {
+			end := s.Start
+			if end.After(until) {
+				end = until
+			}
+			gaps = append(gaps, timeRange{Start: covered, End: end})
+		}
+		if s.End.After(covered) {
+			covered = s.End
+		}
+	}
+	if covered.Before(until) {
+		gaps = append(gaps, timeRange{Start: covered, End: until})
+	}
+	return gaps
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestFindUserShift(t *testing.T) {
+	entries := []pagerduty.RenderedScheduleEntry{
+		{Start: "2026-01-05T09:00:00+01:00", End: "2026-01-12T09:00:00+01:00", User: pagerduty.APIObject{ID: "PALICE", Summary: "Alice"}},
+		{Start: "2026-01-12T09:00:00+01:00", End: "2026-01-19T09:00:00+01:00", User: pagerduty.APIObject{ID: "PBOB", Summary: "Bob"}},
+	}
+	segments, err := renderedSegments(entries)
+	if err != nil {
+		t.Fatalf("renderedSegments: %v", err)
+	}
+	alice := &pagerduty.User{APIObject: pagerduty.APIObject{ID: "PALICE"}, Name: "Alice"}
+
+	tests := []struct {
+		name      string
+		at        string
+		wantStart string
+		wantErr   bool
+	}{
+		{name: "inside shift", at: "2026-01-07T12:00:00+01:00", wantStart: "2026-01-05T09:00:00+01:00"},
+		{name: "start is inclusive", at: "2026-01-05T09:00:00+01:00", wantStart: "2026-01-05T09:00:00+01:00"},
+		{name: "end is exclusive", at: "2026-01-12T09:00:00+01:00", wantErr: true},
+		{name: "somebody else oncall", at: "2026-01-15T12:00:00+01:00", wantErr: true},
+		{name: "nobody oncall", at: "2026-02-01T12:00:00+01:00", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			at, err := time.Parse(time.RFC3339, tc.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seg, err := findUserShift(segments, alice, at)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got segment %v", seg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := seg.Start.Format(time.RFC3339); got != tc.wantStart {
+				t.Fatalf("got shift starting at %s, want %s", got, tc.wantStart)
+			}
+		})
+	}
+}
+
+func TestOverlaySegment(t *testing.T) {
+	at := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	seg := func(start, end, user string) scheduleSegment {
+		return scheduleSegment{Start: at(start), End: at(end), User: pagerduty.APIObject{ID: user}}
+	}
+	alice := seg("2026-01-05T09:00:00Z", "2026-01-12T09:00:00Z", "alice")
+	bob := seg("2026-01-12T09:00:00Z", "2026-01-19T09:00:00Z", "bob")
+
+	tests := []struct {
+		name     string
+		segments []scheduleSegment
+		override scheduleSegment
+		want     []scheduleSegment
+	}{
+		{
+			name:     "inside a shift splits it",
+			segments: []scheduleSegment{alice},
+			override: seg("2026-01-07T09:00:00Z", "2026-01-08T09:00:00Z", "carol"),
+			want: []scheduleSegment{
+				seg("2026-01-05T09:00:00Z", "2026-01-07T09:00:00Z", "alice"),
+				seg("2026-01-07T09:00:00Z", "2026-01-08T09:00:00Z", "carol"),
+				seg("2026-01-08T09:00:00Z", "2026-01-12T09:00:00Z", "alice"),
+			},
+		},
+		{
+			name:     "across a handoff trims both shifts",
+			segments: []scheduleSegment{alice, bob},
+			override: seg("2026-01-11T09:00:00Z", "2026-01-13T09:00:00Z", "carol"),
+			want: []scheduleSegment{
+				seg("2026-01-05T09:00:00Z", "2026-01-11T09:00:00Z", "alice"),
+				seg("2026-01-11T09:00:00Z", "2026-01-13T09:00:00Z", "carol"),
+				seg("2026-01-13T09:00:00Z", "2026-01-19T09:00:00Z", "bob"),
+			},
+		},
+		{
+			name:     "whole shift is replaced",
+			segments: []scheduleSegment{alice, bob},
+			override: seg("2026-01-12T09:00:00Z", "2026-01-19T09:00:00Z", "carol"),
+			want: []scheduleSegment{
+				alice,
+				seg("2026-01-12T09:00:00Z", "2026-01-19T09:00:00Z", "carol"),
+			},
+		},
+		{
+			name:     "gap in the schedule",
+			segments: nil,
+			override: seg("2026-01-12T09:00:00Z", "2026-01-13T09:00:00Z", "carol"),
+			want:     []scheduleSegment{seg("2026-01-12T09:00:00Z", "2026-01-13T09:00:00Z", "carol")},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := overlaySegment(tc.segments, tc.override)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if !hasSegment(got[i:i+1], tc.want[i]) {
+					t.Fatalf("segment %d: got %v (%s), want %v (%s)", i, got[i], got[i].User.ID, tc.want[i], tc.want[i].User.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduleGaps(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2026, 10, 19, h, 0, 0, 0, time.UTC) }
+	seg := func(start, end int) scheduleSegment { return scheduleSegment{Start: at(start), End: at(end)} }
+	tests := []struct {
+		name     string
+		segments []scheduleSegment
+		want     []timeRange
+	}{
+		{name: "fully covered", segments: []scheduleSegment{seg(0, 12), seg(12, 24)}},
+		{name: "empty", want: []timeRange{{at(0), at(24)}}},
+		{name: "gaps at both ends and in the middle", segments: []scheduleSegment{seg(2, 8), seg(10, 20)}, want: []timeRange{{at(0), at(2)}, {at(8), at(10)}, {at(20), at(24)}}},
+		{name: "overlapping layers", segments: []scheduleSegment{seg(0, 18), seg(6, 12), seg(18, 24)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := scheduleGaps(tc.segments, at(0), at(24))
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d gaps (%v), want %d", len(got), got, len(tc.want))
+			}
+			for idx := range got {
+				if !got[idx].Start.Equal(tc.want[idx].Start) || !got[idx].End.Equal(tc.want[idx].End) {
+					t.Errorf("gap %d: got %v, want %v", idx, got[idx], tc.want[idx])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/config"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagOncallShortlistCheckStrict      bool
+	flagOncallShortlistCheckSuggest     bool
+	flagOncallShortlistCheckConcurrency int
+)
+
+func init() {
+	OncallShortlistCmd.AddCommand(OncallShortlistCheckCmd)
+	OncallShortlistCheckCmd.Flags().BoolVar(&flagOncallShortlistCheckStrict, "strict", false, "Exit with an error on warnings too, not only on errors")
+	OncallShortlistCheckCmd.Flags().BoolVar(&flagOncallShortlistCheckSuggest, "suggest", false, "Print pinned schedule IDs for the entries using a query, to paste into the config")
+	OncallShortlistCheckCmd.Flags().IntVarP(&flagOncallShortlistCheckConcurrency, "concurrency", "j", defaultConcurrency, "Number of entries to check concurrently")
+}
+
+var OncallShortlistCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Validate the oncall shortlist against PagerDuty",
+	Long: `Resolve every entry of ` + "`oncall.shortlist`" + ` and report:
+
+  * queries matching no schedule (error) or more than one (warning)
+  * pinned schedule IDs that no longer exist (error)
+  * escalation policies and services that cannot be resolved (error)
+  * entries with nobody currently oncall (warning)
+  * names, components and aliases shared by several entries, which make
+    filtering ambiguous (warning)
+
+With --suggest, the schedules matched by the queries are printed as pinned
+` + "`schedule_id`" + ` values, ready to be pasted back into the config.
+
+The command exits with an error if any error is found, or any warning with
+--strict, so it can be used in CI.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logrus.Debugf("Running oncall shortlist check command")
+		ctx := context.Background()
+		cfg, err := GetConfig()
+		if err != nil {
+			return err
+		}
+		entries := cfg.Oncall.Shortlist
+		if len(entries) == 0 {
+			return fmt.Errorf("no shortlist configured; add entries under `oncall.shortlist` (see the `config-example` subcommand)")
+		}
+		client := pagerduty.NewClient(cfg.PagerDuty.UserToken, withRateLimitRetries())
+		now := time.Now()
+		checks := make([]shortlistCheck, len(entries))
+		forEachConcurrently(flagOncallShortlistCheckConcurrency, len(entries), func(idx int) {
+			checks[idx] = resolveShortlistCheck(ctx, client, entries[idx], now)
+		})
+
+		var findings []auditFinding
+		for _, c := range checks {
+			findings = append(findings, checkShortlistEntry(c)...)
+		}
+		findings = append(findings, duplicateShortlistTerms(entries)...)
+		fmt.Printf("Checked %d shortlist entries\n\n", len(entries))
+		failOn := auditError
+		if flagOncallShortlistCheckStrict {
+			failOn = auditWarning
+		}
+		auditErr := printAuditFindings(findings, failOn)
+		if flagOncallShortlistCheckSuggest {
+			printShortlistSuggestions(checks)
+		}
+		if auditErr != nil {
+			cmd.SilenceUsage = true
+			return auditErr
+		}
+		return nil
+	},
+}
+
+// shortlistCheck is what a shortlist entry resolves to, for checking it.
+type shortlistCheck struct {
+	Entry config.OncallShortlistEntry
+	// Schedules are the schedules matching the entry's query.
+	Schedules []pagerduty.Schedule
+	// ScheduleMissing is set when the pinned schedule does not exist.
+	ScheduleMissing bool
+	// Err is set when the entry cannot be resolved for any other reason.
+	Err error
+	// Oncalls is how many people are currently oncall for the entry.
+	Oncalls int
+}
+
+// resolveShortlistCheck resolves a shortlist entry and who is currently
+// oncall for it.
+func resolveShortlistCheck(ctx context.Context, client *pagerduty.Client, e config.OncallShortlistEntry, now time.Time) shortlistCheck {
+	c := shortlistCheck{Entry: e}
+	opts := pagerduty.ListOnCallOptions{
+		Since: now.Format(time.RFC3339),
+		Until: now.Add(time.Minute).Format(time.RFC3339),
+	}
+	switch {
+	case e.ScheduleID != "":
+		if _, err := client.GetScheduleWithContext(ctx, e.ScheduleID, pagerduty.GetScheduleOptions{}); err != nil {
+			var aerr pagerduty.APIError
+			if errors.As(err, &aerr) && aerr.NotFound() {
+				c.ScheduleMissing = true
+			} else {
+				c.Err = fmt.Errorf("failed to get schedule with ID %q: %w", e.ScheduleID, err)
+			}
+			return c
+		}
+		opts.ScheduleIDs = []string{e.ScheduleID}
+	case e.Query != "":
+		resp, err := client.ListSchedulesWithContext(ctx, pagerduty.ListSchedulesOptions{Query: e.Query})
+		if err != nil {
+			c.Err = fmt.Errorf("failed to search schedules for %q: %w", e.Query, err)
+			return c
+		}
+		c.Schedules = resp.Schedules
+		for _, s := range c.Schedules {
+			opts.ScheduleIDs = append(opts.ScheduleIDs, s.ID)
+		}
+	}
+	if e.HasEscalationPolicy() {
+		ep, _, err := shortlistEscalationPolicy(ctx, client, e)
+		if err != nil {
+			c.Err = err
+			return c
+		}
+		// filters are combined, so schedules and policy need separate requests
+		policyOpts := opts
+		policyOpts.ScheduleIDs = nil
+		policyOpts.EscalationPolicyIDs = []string{ep.ID}
+		oncalls, err := listOnCalls(ctx, client, policyOpts)
+		if err != nil {
+			c.Err = fmt.Errorf("failed to get oncalls of escalation policy %q: %w", ep.Name, err)
+			return c
+		}
+		c.Oncalls += len(oncalls)
+	}
+	if len(opts.ScheduleIDs) > 0 {
+		oncalls, err := listOnCalls(ctx, client, opts)
+		if err != nil {
+			c.Err = fmt.Errorf("failed to get oncalls: %w", err)
+			return c
+		}
+		c.Oncalls += len(oncalls)
+	}
+	return c
+}
+
+// checkShortlistEntry returns the findings about a resolved shortlist entry.
+func checkShortlistEntry(c shortlistCheck) []auditFinding {
+	subject := fmt.Sprintf("shortlist entry %q", c.Entry.Name)
+	if c.Err != nil {
+		return []auditFinding{{Severity: auditError, Subject: subject, Message: c.Err.Error()}}
+	}
+	if c.ScheduleMissing {
+		return []auditFinding{{Severity: auditError, Subject: subject, Message: fmt.Sprintf("pinned schedule %s no longer exists", c.Entry.ScheduleID)}}
+	}
+	var findings []auditFinding
+	if c.Entry.ScheduleID == "" && c.Entry.Query != "" {
+		switch len(c.Schedules) {
+		case 0:
+			return []auditFinding{{Severity: auditError, Subject: subject, Message: fmt.Sprintf("query %q matches no schedule", c.Entry.Query)}}
+		case 1:
+		default:
+			names := make([]string, 0, len(c.Schedules))
+			for _, s := range c.Schedules {
+				names = append(names, fmt.Sprintf("%s [ID: %s]", s.Name, s.ID))
+			}
+			findings = append(findings, auditFinding{
+				Severity: auditWarning,
+				Subject:  subject,
+				Message:  fmt.Sprintf("query %q matches %d schedules (%s), pin one with schedule_id", c.Entry.Query, len(c.Schedules), strings.Join(names, ", ")),
+			})
+		}
+	}
+	if c.Oncalls == 0 {
+		findings = append(findings, auditFinding{Severity: auditWarning, Subject: subject, Message: "nobody is currently oncall"})
+	}
+	return findings
+}
+
+// duplicateShortlistTerms reports names, components and aliases shared by
+// several entries. They are compared like the shortlist filter does, so
+// "bare-metal" and "baremetal" are the same term.
+func duplicateShortlistTerms(entries []config.OncallShortlistEntry) []auditFinding {
+	users := make(map[string][]string)
+	var terms []string
+	for _, e := range entries {
+		seen := make(map[string]struct{})
+		for _, t := range append([]string{e.Name, e.Component}, e.Aliases...) {
+			nt := normalizeTerm(t, false)
+			if nt == "" {
+				continue
+			}
+			if _, ok := seen[nt]; ok {
+				continue
+			}
+			seen[nt] = struct{}{}
+			if _, ok := users[nt]; !ok {
+				terms = append(terms, nt)
+			}
+			users[nt] = append(users[nt], e.Name)
+		}
+	}
+	sort.Strings(terms)
+	var findings []auditFinding
+	for _, t := range terms {
+		if len(users[t]) < 2 {
+			continue
+		}
+		findings = append(findings, auditFinding{
+			Severity: auditWarning,
+			Subject:  "shortlist",
+			Message:  fmt.Sprintf("%q is the name, component or alias of %d entries (%s), filtering by it matches all of them", t, len(users[t]), strings.Join(users[t], ", ")),
+		})
+	}
+	return findings
+}
+
+// suggestedSchedule returns the schedule to pin for an entry using a query:
+// the only schedule matching it, or the one named like the query.
+func suggestedSchedule(c shortlistCheck) *pagerduty.Schedule {
+	if c.Entry.ScheduleID != "" || c.Err != nil {
+		return nil
+	}
+	if len(c.Schedules) == 1 {
+		return &c.Schedules[0]
+	}
+	for idx, s := range c.Schedules {
+		if strings.EqualFold(s.Name, c.Entry.Query) {
+			return &c.Schedules[idx]
+		}
+	}
+	return nil
+}
+
+// printShortlistSuggestions prints the schedule IDs to pin, as a YAML
+// snippet to merge into `oncall.shortlist`.
+func printShortlistSuggestions(checks []shortlistCheck) {
+	var lines []string
+	for _, c := range checks {
+		if s := suggestedSchedule(c); s != nil {
+			lines = append(lines, fmt.Sprintf("  - name: %q\n    schedule_id: %s # %s", c.Entry.Name, s.ID, s.Name))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Printf("\nSuggested pinned schedules for `oncall.shortlist`:\n\n")
+	fmt.Println(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/insomniacslk/sre/pkg/config"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestCheckShortlistEntry(t *testing.T) {
+	sched := func(id, name string) pagerduty.Schedule {
+		return pagerduty.Schedule{APIObject: pagerduty.APIObject{ID: id}, Name: name}
+	}
+	storage := config.OncallShortlistEntry{Name: "Storage", Query: "Storage"}
+	tests := []struct {
+		name          string
+		check         shortlistCheck
+		want          []string
+		wantSuggested string
+	}{
+		{
+			name:          "single schedule",
+			check:         shortlistCheck{Entry: storage, Schedules: []pagerduty.Schedule{sched("P1", "Storage On-Call")}, Oncalls: 1},
+			wantSuggested: "P1",
+		},
+		{
+			name:  "no schedule",
+			check: shortlistCheck{Entry: storage},
+			want:  []string{`ERROR query "Storage" matches no schedule`},
+		},
+		{
+			name:          "several schedules and nobody oncall",
+			check:         shortlistCheck{Entry: storage, Schedules: []pagerduty.Schedule{sched("P1", "Storage Secondary"), sched("P2", "storage")}},
+			want:          []string{`WARNING query "Storage" matches 2 schedules (Storage Secondary [ID: P1], storage [ID: P2]), pin one with schedule_id`, "WARNING nobody is currently oncall"},
+			wantSuggested: "P2",
+		},
+		{
+			name:  "missing pinned schedule",
+			check: shortlistCheck{Entry: config.OncallShortlistEntry{Name: "Storage", ScheduleID: "PGONE"}, ScheduleMissing: true},
+			want:  []string{"ERROR pinned schedule PGONE no longer exists"},
+		},
+		{
+			name:  "unresolvable service",
+			check: shortlistCheck{Entry: config.OncallShortlistEntry{Name: "Storage", ServiceQuery: "storage"}, Err: errors.New(`no service found matching "storage"`)},
+			want:  []string{`ERROR no service found matching "storage"`},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, f := range checkShortlistEntry(tc.check) {
+				got = append(got, f.Severity.String()+" "+f.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("got findings\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+			}
+			gotSuggested := ""
+			if s := suggestedSchedule(tc.check); s != nil {
+				gotSuggested = s.ID
+			}
+			if gotSuggested != tc.wantSuggested {
+				t.Errorf("got suggested schedule %q, want %q", gotSuggested, tc.wantSuggested)
+			}
+		})
+	}
+}
+
+func TestDuplicateShortlistTerms(t *testing.T) {
+	entries := []config.OncallShortlistEntry{
+		{Name: "Hardware", Component: "hardware", Aliases: []string{"bare-metal"}},
+		{Name: "Provisioning", Aliases: []string{"baremetal", "pxe"}},
+		{Name: "Storage", Component: "storage"},
+	}
+	findings := duplicateShortlistTerms(entries)
+	if len(findings) != 1 {
+		t.Fatalf("got %d findings (%+v), want 1", len(findings), findings)
+	}
+	want := `"baremetal" is the name, component or alias of 2 entries (Hardware, Provisioning), filtering by it matches all of them`
+	if findings[0].Message != want {
+		t.Errorf("got %q, want %q", findings[0].Message, want)
+	}
+}
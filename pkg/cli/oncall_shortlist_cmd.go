@@ -11,17 +11,24 @@ import (
 	"github.com/insomniacslk/sre/pkg/config"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	str2duration "github.com/xhit/go-str2duration/v2"
 )
 
 var (
 	flagShortlistCaseSensitive bool
 	flagShortlistExact         bool
+	flagShortlistNext          bool
+	flagShortlistHorizon       string
+	flagShortlistConcurrency   int
 )
 
 func init() {
 	OncallCmd.AddCommand(OncallShortlistCmd)
 	OncallShortlistCmd.Flags().BoolVarP(&flagShortlistCaseSensitive, "case-sensitive", "s", false, "Match the filter case-sensitively (default: case-insensitive)")
 	OncallShortlistCmd.Flags().BoolVarP(&flagShortlistExact, "exact", "e", false, "Require an exact term match instead of fuzzy substring matching")
+	OncallShortlistCmd.Flags().BoolVarP(&flagShortlistNext, "next", "n", false, "Show when the current shift ends and who takes over")
+	OncallShortlistCmd.Flags().StringVarP(&flagShortlistHorizon, "horizon", "H", "", "List the upcoming shifts within this duration, e.g. 7d")
+	OncallShortlistCmd.Flags().IntVarP(&flagShortlistConcurrency, "concurrency", "j", defaultConcurrency, "Number of entries to resolve concurrently")
 }
 
 var OncallShortlistCmd = &cobra.Command{
@@ -33,9 +40,12 @@ var OncallShortlistCmd = &cobra.Command{
 The shortlist is defined under ` + "`oncall.shortlist`" + ` in the config file. Each
 entry maps a component/team name to a PagerDuty schedule, resolved either by a
 free-text ` + "`query`" + ` (like ` + "`oncall search`" + `) or a pinned ` + "`schedule_id`" + `.
+Entries with an ` + "`escalation_policy_id`" + `, or a service referenced by
+` + "`service_id`" + ` or ` + "`service_query`" + `, also show who is oncall at each level of
+the (service's) escalation policy: level 1, level 2 and so on.
 
-With an optional [filter], only entries matching it are shown. Matching is fuzzy
-and forgiving by default:
+With an optional [filter], only entries matching it are shown, best matches
+first. Matching is fuzzy and forgiving by default:
 
   - case-insensitive (use --case-sensitive/-s to require matching case);
   - separator-insensitive, so "bare-metal", "bare_metal" and "baremetal" are
@@ -43,11 +53,24 @@ and forgiving by default:
   - synonym-aware via equivalence groups you define under ` + "`oncall.synonyms`" + `
     (e.g. so "k8s" also matches "kubernetes" and vice-versa), and per-entry
     ` + "`aliases`" + ` keywords matched alongside name/component;
-  - substring-based, so "sec" matches "security". Use --exact/-e to require a
-    whole-term match instead.
+  - substring-based, so "sec" matches "security" (filters shorter than three
+    characters only match the start of words);
+  - typo-tolerant, so "kubernets" matches "kubernetes".
+
+Whole-term matches rank first, then word prefixes, substrings and typos. Use
+--exact/-e to require a whole-term match instead. When nothing matches, the
+closest components are suggested.
 
 The filter is matched against each entry's component, name and aliases, e.g.
-"oncall shortlist k8s".`,
+"oncall shortlist k8s".
+
+With --next/-n, each schedule also shows when the current shift ends and who
+takes over. With --horizon/-H, e.g. "--horizon 7d", the full sequence of shifts
+within that duration is listed.
+
+Entries are resolved concurrently, up to --concurrency/-j at a time, and the
+current oncalls of all the pinned schedules are fetched with a single request.
+Requests rate limited by PagerDuty are retried.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		logrus.Debugf("Running oncall shortlist command")
@@ -65,12 +88,35 @@ The filter is matched against each entry's component, name and aliases, e.g.
 		filter := strings.TrimSpace(strings.Join(args, " "))
 		selected := selectShortlistEntries(entries, filter, cfg.Oncall.Synonyms, flagShortlistExact, flagShortlistCaseSensitive)
 		if len(selected) == 0 {
-			fmt.Printf("No shortlist entries match %q\n", filter)
+			fmt.Printf("No shortlist entries match %q%s\n", filter, didYouMean(entries, filter, flagShortlistCaseSensitive))
 			return nil
 		}
 
-		client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
-		until := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+		now := time.Now()
+		horizon := shiftSearchWindow
+		if flagShortlistHorizon != "" {
+			horizon, err = str2duration.ParseDuration(flagShortlistHorizon)
+			if err != nil {
+				return fmt.Errorf("failed to parse horizon %q: %w", flagShortlistHorizon, err)
+			}
+		}
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return fmt.Errorf("cannot load timezone %q: %w", cfg.Timezone, err)
+		}
+
+		client := pagerduty.NewClient(cfg.PagerDuty.UserToken, withRateLimitRetries())
+		until := now.Add(24 * time.Hour).Format(time.RFC3339)
+
+		pinned, err := pinnedShortlistOncalls(ctx, client, selected, until)
+		if err != nil {
+			logrus.Warningf("%v, resolving pinned schedules one by one", err)
+		}
+		withShifts := flagShortlistNext || flagShortlistHorizon != ""
+		results := make([]shortlistResult, len(selected))
+		forEachConcurrently(flagShortlistConcurrency, len(selected), func(idx int) {
+			results[idx] = resolveShortlistEntry(ctx, client, selected[idx], pinned, until, now, horizon, withShifts, loc)
+		})
 
 		if filter == "" {
 			fmt.Printf("Oncall shortlist (%d entries)\n", len(selected))
@@ -78,32 +124,7 @@ The filter is matched against each entry's component, name and aliases, e.g.
 			fmt.Printf("Oncall shortlist matching %q (%d entries)\n", filter, len(selected))
 		}
 		for idx, e := range selected {
-			label := ansi.Bold(e.Name)
-			oncalls, err := resolveShortlistOncalls(ctx, client, e, until)
-			if err != nil {
-				fmt.Printf("%d) %s — error: %v\n", idx+1, label, err)
-				continue
-			}
-			if len(oncalls) == 0 {
-				where := e.Query
-				if e.ScheduleID != "" {
-					where = "schedule " + e.ScheduleID
-				}
-				fmt.Printf("%d) %s — no current oncall found (%s)\n", idx+1, label, where)
-				continue
-			}
-			for _, oc := range oncalls {
-				sched := oc.Schedule.Summary
-				if oc.Schedule.HTMLURL != "" {
-					sched = ansi.ToURL(sched, oc.Schedule.HTMLURL)
-				}
-				user := oc.User.Summary
-				if oc.User.HTMLURL != "" {
-					user = ansi.ToURL(user, oc.User.HTMLURL)
-				}
-				fmt.Printf("%d) %s — %s [ID: %s] oncall: %s (%s)\n",
-					idx+1, label, sched, oc.Schedule.ID, user, oc.User.Email)
-			}
+			printShortlistResult(idx+1, e, results[idx], now, horizon, loc)
 		}
 		return nil
 	},
@@ -119,20 +140,12 @@ func resolveShortlistOncalls(
 	e config.OncallShortlistEntry,
 	until string,
 ) ([]pagerduty.OnCall, error) {
-	var scheduleIDs []string
-	if e.ScheduleID != "" {
-		scheduleIDs = []string{e.ScheduleID}
-	} else {
-		sResp, err := client.ListSchedulesWithContext(ctx, pagerduty.ListSchedulesOptions{Query: e.Query})
-		if err != nil {
-			return nil, fmt.Errorf("failed to search schedules for %q: %w", e.Query, err)
-		}
-		for _, sc := range sResp.Schedules {
-			scheduleIDs = append(scheduleIDs, sc.ID)
-		}
-		if len(scheduleIDs) == 0 {
-			return nil, nil
-		}
+	scheduleIDs, err := shortlistScheduleIDs(ctx, client, e)
+	if err != nil {
+		return nil, err
+	}
+	if len(scheduleIDs) == 0 {
+		return nil, nil
 	}
 
 	resp, err := client.ListOnCallsWithContext(ctx, pagerduty.ListOnCallOptions{
@@ -143,17 +156,355 @@ func resolveShortlistOncalls(
 	if err != nil {
 		return nil, fmt.Errorf("failed to get oncalls: %w", err)
 	}
+	return currentOncallPerSchedule(resp.OnCalls), nil
+}
 
-	// Keep only the first (current) oncall per schedule so a multi-layer
-	// escalation policy doesn't print several rows for one schedule.
+// currentOncallPerSchedule keeps only the first (current) oncall per schedule
+// so a multi-layer escalation policy doesn't print several rows for one
+// schedule.
+func currentOncallPerSchedule(oncalls []pagerduty.OnCall) []pagerduty.OnCall {
 	seen := make(map[string]struct{})
-	out := make([]pagerduty.OnCall, 0, len(resp.OnCalls))
-	for _, oc := range resp.OnCalls {
+	out := make([]pagerduty.OnCall, 0, len(oncalls))
+	for _, oc := range oncalls {
 		if _, ok := seen[oc.Schedule.ID]; ok {
 			continue
 		}
 		seen[oc.Schedule.ID] = struct{}{}
 		out = append(out, oc)
 	}
-	return out, nil
+	return out
+}
+
+// pinnedShortlistOncalls fetches the current oncalls of the pinned schedules
+// of all the entries with a single (paginated) request, indexed by schedule
+// ID. Every pinned schedule has a key, even if nobody is oncall for it.
+func pinnedShortlistOncalls(ctx context.Context, client *pagerduty.Client, entries []config.OncallShortlistEntry, until string) (map[string][]pagerduty.OnCall, error) {
+	pinned := make(map[string][]pagerduty.OnCall)
+	var scheduleIDs []string
+	for _, e := range entries {
+		if _, ok := pinned[e.ScheduleID]; e.ScheduleID == "" || ok {
+			continue
+		}
+		pinned[e.ScheduleID] = nil
+		scheduleIDs = append(scheduleIDs, e.ScheduleID)
+	}
+	if len(scheduleIDs) == 0 {
+		return nil, nil
+	}
+	oncalls, err := listOnCalls(ctx, client, pagerduty.ListOnCallOptions{
+		ScheduleIDs: scheduleIDs,
+		Includes:    []string{"users"},
+		Until:       until,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oncalls of the pinned schedules: %w", err)
+	}
+	for _, oc := range currentOncallPerSchedule(oncalls) {
+		pinned[oc.Schedule.ID] = append(pinned[oc.Schedule.ID], oc)
+	}
+	return pinned, nil
+}
+
+// shortlistResult is a resolved shortlist entry: the levels of its
+// escalation policy, if it has one, and the current oncall of its schedules.
+type shortlistResult struct {
+	Policy    *pagerduty.EscalationPolicy
+	Service   *pagerduty.Service
+	Graph     *escalationPolicyGraph
+	PolicyErr error
+	Oncalls   []shortlistOncall
+	Err       error
+}
+
+// shortlistOncall is the current oncall of a schedule, with the upcoming
+// shifts of the schedule when requested.
+type shortlistOncall struct {
+	pagerduty.OnCall
+	Shifts    []scheduleSegment
+	ShiftsErr error
+}
+
+// resolveShortlistEntry resolves everything printed for a shortlist entry.
+// pinned holds the oncalls of the pinned schedules, if they were fetched
+// already.
+func resolveShortlistEntry(ctx context.Context, client *pagerduty.Client, e config.OncallShortlistEntry, pinned map[string][]pagerduty.OnCall, until string, now time.Time, horizon time.Duration, withShifts bool, loc *time.Location) shortlistResult {
+	var r shortlistResult
+	if e.HasEscalationPolicy() {
+		r.Policy, r.Service, r.PolicyErr = shortlistEscalationPolicy(ctx, client, e)
+		if r.PolicyErr == nil {
+			r.Graph, r.PolicyErr = buildEscalationPolicyGraph(ctx, client, *r.Policy, now)
+		}
+		if !e.HasSchedule() {
+			return r
+		}
+	}
+	oncalls, ok := pinned[e.ScheduleID]
+	if e.ScheduleID == "" || !ok {
+		oncalls, r.Err = resolveShortlistOncalls(ctx, client, e, until)
+		if r.Err != nil {
+			return r
+		}
+	}
+	for _, oc := range oncalls {
+		soc := shortlistOncall{OnCall: oc}
+		if withShifts {
+			var segments []scheduleSegment
+			_, segments, soc.ShiftsErr = fetchSchedule(ctx, client, oc.Schedule.ID, now, now.Add(horizon), loc.String())
+			soc.Shifts = mergeSegments(segments)
+		}
+		r.Oncalls = append(r.Oncalls, soc)
+	}
+	return r
+}
+
+// printShortlistResult prints a resolved shortlist entry.
+func printShortlistResult(num int, e config.OncallShortlistEntry, r shortlistResult, now time.Time, horizon time.Duration, loc *time.Location) {
+	label := ansi.Bold(e.Name)
+	if e.HasEscalationPolicy() {
+		printShortlistLevels(num, label, r)
+		if !e.HasSchedule() {
+			return
+		}
+	}
+	if r.Err != nil {
+		fmt.Printf("%d) %s — error: %v\n", num, label, r.Err)
+		return
+	}
+	if len(r.Oncalls) == 0 {
+		where := e.Query
+		if e.ScheduleID != "" {
+			where = "schedule " + e.ScheduleID
+		}
+		fmt.Printf("%d) %s — no current oncall found (%s)\n", num, label, where)
+		return
+	}
+	for _, oc := range r.Oncalls {
+		sched := oc.Schedule.Summary
+		if oc.Schedule.HTMLURL != "" {
+			sched = ansi.ToURL(sched, oc.Schedule.HTMLURL)
+		}
+		user := oc.User.Summary
+		if oc.User.HTMLURL != "" {
+			user = ansi.ToURL(user, oc.User.HTMLURL)
+		}
+		fmt.Printf("%d) %s — %s [ID: %s] oncall: %s (%s)\n",
+			num, label, sched, oc.Schedule.ID, user, oc.User.Email)
+		if flagShortlistNext || flagShortlistHorizon != "" {
+			printShortlistHandoffs(oc, now, horizon, loc)
+		}
+	}
+}
+
+// shortlistScheduleIDs returns the IDs of the schedules of a shortlist entry,
+// either its pinned ScheduleID or the schedules matching its Query. Entries
+// referencing only an escalation policy resolve to the schedules targeted by
+// the policy.
+func shortlistScheduleIDs(ctx context.Context, client *pagerduty.Client, e config.OncallShortlistEntry) ([]string, error) {
+	if e.ScheduleID != "" {
+		return []string{e.ScheduleID}, nil
+	}
+	if e.Query == "" {
+		if !e.HasEscalationPolicy() {
+			return nil, nil
+		}
+		ep, _, err := shortlistEscalationPolicy(ctx, client, e)
+		if err != nil {
+			return nil, err
+		}
+		return policyScheduleIDs(*ep), nil
+	}
+	resp, err := client.ListSchedulesWithContext(ctx, pagerduty.ListSchedulesOptions{Query: e.Query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search schedules for %q: %w", e.Query, err)
+	}
+	scheduleIDs := make([]string, 0, len(resp.Schedules))
+	for _, sc := range resp.Schedules {
+		scheduleIDs = append(scheduleIDs, sc.ID)
+	}
+	return scheduleIDs, nil
+}
+
+// printShortlistHandoffs prints when the current shift of a schedule ends and
+// who takes over (with --next), and the shifts within the horizon (with
+// --horizon).
+func printShortlistHandoffs(oc shortlistOncall, now time.Time, horizon time.Duration, loc *time.Location) {
+	if oc.ShiftsErr != nil {
+		fmt.Printf("    error: %v\n", oc.ShiftsErr)
+		return
+	}
+	shifts := oc.Shifts
+	if flagShortlistNext {
+		current, next := nextHandoff(shifts, now)
+		switch {
+		case next == nil && current != nil:
+			fmt.Printf("    shift ends after %s, no handoff within %s\n", current.End.In(loc).Format(shiftTimeFormat), horizon)
+		case next == nil:
+			fmt.Printf("    nobody oncall within %s\n", horizon)
+		default:
+			fmt.Printf("    next: %s from %s (in %s)\n", ansi.ToURL(next.User.Summary, next.User.HTMLURL), next.Start.In(loc).Format(shiftTimeFormat), next.Start.Sub(now).Round(time.Minute))
+		}
+	}
+	if flagShortlistHorizon != "" {
+		for _, s := range shifts {
+			fmt.Printf("    %s - %s  %s\n", s.Start.In(loc).Format(shiftTimeFormat), s.End.In(loc).Format(shiftTimeFormat), ansi.ToURL(s.User.Summary, s.User.HTMLURL))
+		}
+	}
+}
+
+// mergeSegments merges adjacent segments of the same user, so that a shift
+// split across layers or rotations is reported as a single one.
+func mergeSegments(segments []scheduleSegment) []scheduleSegment {
+	var out []scheduleSegment
+	for _, s := range segments {
+		if n := len(out); n > 0 && out[n-1].User.ID == s.User.ID && out[n-1].End.Equal(s.Start) {
+			out[n-1].End = s.End
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// nextHandoff returns the shift in progress at now, if any, and the one
+// following it. Shifts must be merged and sorted by start time.
+func nextHandoff(shifts []scheduleSegment, now time.Time) (*scheduleSegment, *scheduleSegment) {
+	for idx := range shifts {
+		if shifts[idx].Contains(now) {
+			if idx+1 < len(shifts) {
+				return &shifts[idx], &shifts[idx+1]
+			}
+			return &shifts[idx], nil
+		}
+		if shifts[idx].Start.After(now) {
+			return nil, &shifts[idx]
+		}
+	}
+	return nil, nil
+}
+
+// printShortlistLevels prints who is oncall at each level of the escalation
+// policy of a resolved shortlist entry.
+func printShortlistLevels(num int, label string, r shortlistResult) {
+	if r.Service != nil {
+		label += " — service " + ansi.ToURL(r.Service.Name, r.Service.HTMLURL)
+	}
+	if r.PolicyErr != nil {
+		fmt.Printf("%d) %s — error: %v\n", num, label, r.PolicyErr)
+		return
+	}
+	ep, g := r.Policy, r.Graph
+	fmt.Printf("%d) %s — %s [ID: %s]\n", num, label, ansi.ToURL(ep.Name, ep.HTMLURL), ep.ID)
+	for _, l := range g.Levels {
+		oncalls := levelOncalls(l)
+		if len(oncalls) == 0 {
+			fmt.Printf("    level %d: nobody oncall\n", l.Number)
+			continue
+		}
+		names := make([]string, 0, len(oncalls))
+		for _, oc := range oncalls {
+			name := formatOncallUser(oc.User)
+			if oc.Schedule != "" {
+				name += " via " + oc.Schedule
+			}
+			names = append(names, name)
+		}
+		fmt.Printf("    level %d: %s\n", l.Number, strings.Join(names, ", "))
+	}
+}
+
+// shortlistEscalationPolicy returns the escalation policy of a shortlist
+// entry, either referenced directly or through a service. In the latter case
+// the service is returned too.
+func shortlistEscalationPolicy(ctx context.Context, client *pagerduty.Client, e config.OncallShortlistEntry) (*pagerduty.EscalationPolicy, *pagerduty.Service, error) {
+	if e.EscalationPolicyID != "" {
+		ep, err := lookupEscalationPolicy(ctx, client, e.EscalationPolicyID, false)
+		return ep, nil, err
+	}
+	var service *pagerduty.Service
+	if e.ServiceID != "" {
+		s, err := client.GetServiceWithContext(ctx, e.ServiceID, &pagerduty.GetServiceOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get service with ID %q: %w", e.ServiceID, err)
+		}
+		service = s
+	} else {
+		resp, err := client.ListServicesWithContext(ctx, pagerduty.ListServiceOptions{Query: e.ServiceQuery})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to search services for %q: %w", e.ServiceQuery, err)
+		}
+		service, err = pickShortlistService(resp.Services, e.ServiceQuery)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	ep, err := lookupEscalationPolicy(ctx, client, service.EscalationPolicy.ID, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ep, service, nil
+}
+
+// pickShortlistService returns the only service matching a shortlist entry's
+// service query, or the one whose name is the query (case-insensitive) when
+// several match. Unlike lookupService it never prompts, since the shortlist
+// resolves several entries at once.
+func pickShortlistService(services []pagerduty.Service, query string) (*pagerduty.Service, error) {
+	switch len(services) {
+	case 0:
+		return nil, fmt.Errorf("no service found matching %q", query)
+	case 1:
+		return &services[0], nil
+	}
+	names := make([]string, 0, len(services))
+	for idx, s := range services {
+		if strings.EqualFold(s.Name, query) {
+			return &services[idx], nil
+		}
+		names = append(names, s.Name)
+	}
+	return nil, fmt.Errorf("%d services match %q (%s), use a more specific `service_query` or a `service_id`", len(services), query, strings.Join(names, ", "))
+}
+
+// policyScheduleIDs returns the IDs of the schedules targeted by an
+// escalation policy, in level order.
+func policyScheduleIDs(ep pagerduty.EscalationPolicy) []string {
+	var ids []string
+	seen := make(map[string]struct{})
+	for _, r := range ep.EscalationRules {
+		for _, t := range r.Targets {
+			if _, ok := seen[t.ID]; ok || !(escalationTarget{Target: t}).isScheduleTarget() {
+				continue
+			}
+			seen[t.ID] = struct{}{}
+			ids = append(ids, t.ID)
+		}
+	}
+	return ids
+}
+
+// levelOncall is somebody oncall at an escalation level, with the schedule
+// they are oncall for, if they are not targeted directly.
+type levelOncall struct {
+	User     pagerduty.User
+	Schedule string
+}
+
+// levelOncalls returns who is oncall at an escalation level, once per user.
+func levelOncalls(l escalationLevel) []levelOncall {
+	var out []levelOncall
+	seen := make(map[string]struct{})
+	for _, t := range l.Targets {
+		for _, u := range t.Oncall {
+			if _, ok := seen[u.ID]; ok {
+				continue
+			}
+			seen[u.ID] = struct{}{}
+			oc := levelOncall{User: u}
+			if t.isScheduleTarget() {
+				oc.Schedule = t.Target.Summary
+			}
+			out = append(out, oc)
+		}
+	}
+	return out
 }
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestNextHandoff(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2026, 10, 19, h, 0, 0, 0, time.UTC) }
+	seg := func(start, end int, user string) scheduleSegment {
+		return scheduleSegment{Start: at(start), End: at(end), User: pagerduty.APIObject{ID: user}}
+	}
+	// Alice's shift is split in two adjacent segments, and nobody is
+	// oncall between 18 and 20
+	shifts := mergeSegments([]scheduleSegment{seg(0, 6, "ALICE"), seg(6, 12, "ALICE"), seg(12, 18, "BOB"), seg(20, 24, "ALICE")})
+	if len(shifts) != 3 {
+		t.Fatalf("mergeSegments: got %d shifts (%v), want 3", len(shifts), shifts)
+	}
+	tests := []struct {
+		name        string
+		now         int
+		wantCurrent string
+		wantNext    string
+		wantStart   int
+	}{
+		{name: "handoff after a merged shift", now: 3, wantCurrent: "ALICE", wantNext: "BOB", wantStart: 12},
+		{name: "handoff after a gap", now: 15, wantCurrent: "BOB", wantNext: "ALICE", wantStart: 20},
+		{name: "nobody oncall now", now: 19, wantNext: "ALICE", wantStart: 20},
+		{name: "last shift", now: 22, wantCurrent: "ALICE"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			current, next := nextHandoff(shifts, at(tc.now))
+			if (current == nil) != (tc.wantCurrent == "") || (current != nil && current.User.ID != tc.wantCurrent) {
+				t.Errorf("current: got %v, want %q", current, tc.wantCurrent)
+			}
+			if (next == nil) != (tc.wantNext == "") {
+				t.Fatalf("next: got %v, want %q", next, tc.wantNext)
+			}
+			if next != nil && (next.User.ID != tc.wantNext || !next.Start.Equal(at(tc.wantStart))) {
+				t.Errorf("next: got %s from %s, want %s from %s", next.User.ID, next.Start, tc.wantNext, at(tc.wantStart))
+			}
+		})
+	}
+}
+
+func TestLevelOncalls(t *testing.T) {
+	alice := pagerduty.User{APIObject: pagerduty.APIObject{ID: "ALICE"}, Name: "Alice"}
+	bob := pagerduty.User{APIObject: pagerduty.APIObject{ID: "BOB"}, Name: "Bob"}
+	l := escalationLevel{
+		Number: 2,
+		Targets: []escalationTarget{
+			{Target: pagerduty.APIObject{ID: "S1", Type: "schedule_reference", Summary: "Secondary"}, Oncall: []pagerduty.User{alice}},
+			{Target: pagerduty.APIObject{ID: "S2", Type: "schedule_reference", Summary: "Backup"}, Oncall: []pagerduty.User{alice}},
+			{Target: pagerduty.APIObject{ID: "S3", Type: "schedule_reference", Summary: "Empty"}},
+			{Target: pagerduty.APIObject{ID: "BOB", Type: "user_reference", Summary: "Bob"}, Oncall: []pagerduty.User{bob}},
+		},
+	}
+	got := levelOncalls(l)
+	want := []levelOncall{{User: alice, Schedule: "Secondary"}, {User: bob}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d oncalls (%+v), want %d", len(got), got, len(want))
+	}
+	for idx := range got {
+		if got[idx].User.ID != want[idx].User.ID || got[idx].Schedule != want[idx].Schedule {
+			t.Errorf("oncall %d: got %s via %q, want %s via %q", idx, got[idx].User.ID, got[idx].Schedule, want[idx].User.ID, want[idx].Schedule)
+		}
+	}
+}
+
+func TestPickShortlistService(t *testing.T) {
+	svc := func(id, name string) pagerduty.Service {
+		return pagerduty.Service{APIObject: pagerduty.APIObject{ID: id}, Name: name}
+	}
+	tests := []struct {
+		name     string
+		services []pagerduty.Service
+		query    string
+		want     string
+		wantErr  bool
+	}{
+		{name: "no match", query: "db", wantErr: true},
+		{name: "single match", services: []pagerduty.Service{svc("P1", "Database Service")}, query: "database", want: "P1"},
+		{name: "exact match wins", services: []pagerduty.Service{svc("P1", "Database Service Staging"), svc("P2", "database service")}, query: "Database Service", want: "P2"},
+		{name: "ambiguous", services: []pagerduty.Service{svc("P1", "Database Primary"), svc("P2", "Database Replica")}, query: "database", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pickShortlistService(tc.services, tc.query)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got service %s", got.ID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.ID != tc.want {
+				t.Errorf("got service %s, want %s", got.ID, tc.want)
+			}
+		})
+	}
+}
+
+func TestPolicyScheduleIDs(t *testing.T) {
+	ep := pagerduty.EscalationPolicy{
+		EscalationRules: []pagerduty.EscalationRule{
+			{Targets: []pagerduty.APIObject{{ID: "S1", Type: "schedule_reference"}, {ID: "U1", Type: "user_reference"}}},
+			{Targets: []pagerduty.APIObject{{ID: "S2", Type: "schedule_reference"}, {ID: "S1", Type: "schedule_reference"}}},
+		},
+	}
+	got := policyScheduleIDs(ep)
+	if len(got) != 2 || got[0] != "S1" || got[1] != "S2" {
+		t.Errorf("got %v, want [S1 S2]", got)
+	}
+}
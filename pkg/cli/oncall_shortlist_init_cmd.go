@@ -0,0 +1,405 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/insomniacslk/sre/pkg/config"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	flagOncallShortlistInitTeams    []string
+	flagOncallShortlistInitServices bool
+	flagOncallShortlistInitMerge    bool
+	flagOncallShortlistInitOutput   string
+	flagOncallShortlistInitYes      bool
+)
+
+func init() {
+	OncallShortlistCmd.AddCommand(OncallShortlistInitCmd)
+	OncallShortlistInitCmd.Flags().StringSliceVarP(&flagOncallShortlistInitTeams, "team", "t", nil, "Team to discover schedules for, either a name query or a team ID prefixed by \"+\", can be repeated (default: the teams in pagerduty.teams)")
+	OncallShortlistInitCmd.Flags().BoolVar(&flagOncallShortlistInitServices, "services", false, "Also propose an entry for each service of the teams")
+	OncallShortlistInitCmd.Flags().BoolVar(&flagOncallShortlistInitMerge, "merge", false, "Merge the entries into the config file instead of printing them")
+	OncallShortlistInitCmd.Flags().StringVarP(&flagOncallShortlistInitOutput, "output", "o", "", "Write the YAML snippet to this file instead of stdout")
+	OncallShortlistInitCmd.Flags().BoolVarP(&flagOncallShortlistInitYes, "yes", "y", false, "Keep all the proposed entries and do not ask for confirmation")
+}
+
+var OncallShortlistInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Bootstrap the oncall shortlist from the schedules of the configured teams",
+	Long: `Discover the schedules (and, with --services, the services) of the teams in
+` + "`pagerduty.teams`" + ` (or passed via --team), and propose a shortlist entry for each
+of them, with a name, component and aliases derived from its name and a pinned
+` + "`schedule_id`" + ` (or ` + "`service_id`" + `). Schedules and services already in the shortlist,
+pinned or matched by a query, are skipped.
+
+The proposed entries are listed and can be deselected interactively, then
+printed as a YAML snippet to paste under ` + "`oncall`" + `, or with --merge added to the
+` + "`oncall.shortlist`" + ` of the config file in use. Merging rewrites the whole config
+file: its comments are preserved, but blank lines are dropped and it is
+re-indented with 2 spaces. Print the snippet instead to keep the formatting.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logrus.Debugf("Running oncall shortlist init command")
+		ctx := context.Background()
+		cfg, err := GetConfig()
+		if err != nil {
+			return err
+		}
+		teams := cfg.PagerDuty.Teams
+		if len(flagOncallShortlistInitTeams) > 0 {
+			teams = flagOncallShortlistInitTeams
+		}
+		client := pagerduty.NewClient(cfg.PagerDuty.UserToken, withRateLimitRetries())
+		teamIDs, err := resolveTeamIDs(ctx, client, teams)
+		if err != nil {
+			return err
+		}
+		schedules, err := listSchedules(ctx, client, pagerduty.ListSchedulesOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to list schedules: %w", err)
+		}
+		schedules = teamSchedules(schedules, teamIDs)
+		var services []pagerduty.Service
+		if flagOncallShortlistInitServices {
+			services, err = client.ListServicesPaginated(ctx, pagerduty.ListServiceOptions{TeamIDs: teamIDs})
+			if err != nil {
+				return fmt.Errorf("failed to list services: %w", err)
+			}
+		}
+
+		coveredSchedules, coveredServices := shortlistCoverage(ctx, client, cfg.Oncall.Shortlist)
+		proposed := proposeShortlistEntries(schedules, services, coveredSchedules, coveredServices)
+		if len(proposed) == 0 {
+			fmt.Fprintf(os.Stderr, "Nothing to add, all the schedules of the teams are in the shortlist already\n")
+			return nil
+		}
+		if !flagOncallShortlistInitYes && stdinIsTerminal() {
+			proposed, err = deselectShortlistEntries(proposed)
+			if err != nil {
+				return err
+			}
+			if len(proposed) == 0 {
+				fmt.Printf("\nAborting\n")
+				return nil
+			}
+		}
+
+		if flagOncallShortlistInitMerge {
+			return mergeShortlistIntoConfig(viper.ConfigFileUsed(), proposed, flagOncallShortlistInitYes)
+		}
+		return writeOutput(flagOncallShortlistInitOutput, func(w io.Writer) error {
+			return writeShortlistSnippet(w, proposed)
+		})
+	},
+}
+
+// shortlistInitEntry is a proposed shortlist entry, in the format of the
+// config file.
+type shortlistInitEntry struct {
+	Name       string   `yaml:"name"`
+	Component  string   `yaml:"component,omitempty"`
+	Aliases    []string `yaml:"aliases,omitempty,flow"`
+	ScheduleID string   `yaml:"schedule_id,omitempty"`
+	ServiceID  string   `yaml:"service_id,omitempty"`
+	// Source is the name of the schedule or service, written as a comment.
+	Source string `yaml:"-"`
+}
+
+// teamSchedules returns the schedules belonging to any of the teams.
+func teamSchedules(schedules []pagerduty.Schedule, teamIDs []string) []pagerduty.Schedule {
+	var out []pagerduty.Schedule
+	for _, s := range schedules {
+		for _, t := range s.Teams {
+			if slices.Contains(teamIDs, t.ID) {
+				out = append(out, s)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// shortlistNoiseWords are dropped from schedule and service names when
+// deriving entry names, e.g. "Storage Team On-Call" becomes "Storage". They
+// are compared normalized.
+var shortlistNoiseWords = map[string]struct{}{
+	"oncall":    {},
+	"on":        {},
+	"call":      {},
+	"schedule":  {},
+	"rotation":  {},
+	"primary":   {},
+	"team":      {},
+	"pagerduty": {},
+	"service":   {},
+}
+
+// shortlistTerms derives the name, component and aliases of an entry from the
+// name of a schedule or service.
+func shortlistTerms(name string) (string, string, []string) {
+	var words []string
+	for _, w := range strings.Fields(strings.NewReplacer("(", " ", ")", " ", "-", " ", "_", " ", "/", " ").Replace(name)) {
+		if _, ok := shortlistNoiseWords[normalizeTerm(w, false)]; !ok {
+			words = append(words, w)
+		}
+	}
+	if len(words) == 0 {
+		words = strings.Fields(name)
+	}
+	entryName := strings.Join(words, " ")
+	lower := make([]string, 0, len(words))
+	for _, w := range words {
+		lower = append(lower, strings.ToLower(w))
+	}
+	component := strings.Join(lower, "-")
+	var aliases []string
+	if len(lower) > 1 {
+		for _, w := range lower {
+			if len(w) >= 3 && !slices.Contains(aliases, w) {
+				aliases = append(aliases, w)
+			}
+		}
+	}
+	return entryName, component, aliases
+}
+
+// shortlistCoverage returns the IDs of the schedules and services already
+// covered by the shortlist, resolving the queries of its entries. Entries that
+// fail to resolve are reported and skipped, since checking them is the job of
+// `shortlist check`.
+func shortlistCoverage(ctx context.Context, client *pagerduty.Client, entries []config.OncallShortlistEntry) (map[string]struct{}, map[string]struct{}) {
+	scheduleIDs := make([][]string, len(entries))
+	serviceIDs := make([]string, len(entries))
+	errs := make([]error, len(entries))
+	forEachConcurrently(defaultConcurrency, len(entries), func(idx int) {
+		e := entries[idx]
+		scheduleIDs[idx], errs[idx] = shortlistScheduleIDs(ctx, client, e)
+		if errs[idx] != nil {
+			return
+		}
+		switch {
+		case e.ServiceID != "":
+			serviceIDs[idx] = e.ServiceID
+		case e.ServiceQuery != "":
+			var service *pagerduty.Service
+			_, service, errs[idx] = shortlistEscalationPolicy(ctx, client, e)
+			if service != nil {
+				serviceIDs[idx] = service.ID
+			}
+		}
+	})
+	schedules := make(map[string]struct{})
+	services := make(map[string]struct{})
+	for idx, e := range entries {
+		if errs[idx] != nil {
+			logrus.Warningf("Cannot resolve shortlist entry %q, its schedules and services may be proposed again: %v", e.Name, errs[idx])
+		}
+		for _, id := range scheduleIDs[idx] {
+			schedules[id] = struct{}{}
+		}
+		if serviceIDs[idx] != "" {
+			services[serviceIDs[idx]] = struct{}{}
+		}
+	}
+	return schedules, services
+}
+
+// proposeShortlistEntries proposes an entry per schedule and service, skipping
+// the ones already covered by the shortlist. Entries are sorted by name.
+func proposeShortlistEntries(schedules []pagerduty.Schedule, services []pagerduty.Service, coveredSchedules, coveredServices map[string]struct{}) []shortlistInitEntry {
+	var out []shortlistInitEntry
+	for _, s := range schedules {
+		if _, ok := coveredSchedules[s.ID]; ok {
+			continue
+		}
+		name, component, aliases := shortlistTerms(s.Name)
+		out = append(out, shortlistInitEntry{Name: name, Component: component, Aliases: aliases, ScheduleID: s.ID, Source: s.Name})
+	}
+	for _, s := range services {
+		if _, ok := coveredServices[s.ID]; ok {
+			continue
+		}
+		name, component, aliases := shortlistTerms(s.Name)
+		out = append(out, shortlistInitEntry{Name: name, Component: component, Aliases: aliases, ServiceID: s.ID, Source: s.Name})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name)
+	})
+	return out
+}
+
+// deselectShortlistEntries lists the proposed entries and lets the user
+// deselect some of them.
+func deselectShortlistEntries(entries []shortlistInitEntry) ([]shortlistInitEntry, error) {
+	items := make([]string, 0, len(entries))
+	for _, e := range entries {
+		kind := "schedule"
+		if e.ServiceID != "" {
+			kind = "service"
+		}
+		items = append(items, fmt.Sprintf("%s (component: %s, %s: %s)", e.Name, e.Component, kind, e.Source))
+	}
+	keep, err := deselect("proposed shortlist entries", items)
+	if err != nil {
+		return nil, err
+	}
+	var out []shortlistInitEntry
+	for _, idx := range keep {
+		out = append(out, entries[idx])
+	}
+	return out, nil
+}
+
+// shortlistEntryNode encodes an entry as a YAML node, commenting the pinned
+// ID with the name of the schedule or service.
+func shortlistEntryNode(e shortlistInitEntry) (*yaml.Node, error) {
+	var n yaml.Node
+	if err := n.Encode(e); err != nil {
+		return nil, fmt.Errorf("failed to encode shortlist entry %q: %w", e.Name, err)
+	}
+	for idx := 0; idx+1 < len(n.Content); idx += 2 {
+		if key := n.Content[idx].Value; key == "schedule_id" || key == "service_id" {
+			n.Content[idx+1].LineComment = e.Source
+		}
+	}
+	return &n, nil
+}
+
+// writeShortlistSnippet writes the entries as a YAML snippet to paste under
+// `oncall`.
+func writeShortlistSnippet(w io.Writer, entries []shortlistInitEntry) error {
+	seq := yaml.Node{Kind: yaml.SequenceNode}
+	for _, e := range entries {
+		n, err := shortlistEntryNode(e)
+		if err != nil {
+			return err
+		}
+		seq.Content = append(seq.Content, n)
+	}
+	doc := yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "shortlist"},
+		&seq,
+	}}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode shortlist: %w", err)
+	}
+	return enc.Close()
+}
+
+// mergeShortlistEntries appends the entries to `oncall.shortlist` in a parsed
+// config file, creating the keys if needed. Comments in the document are
+// preserved.
+func mergeShortlistEntries(doc *yaml.Node, entries []shortlistInitEntry) error {
+	if doc.Kind == 0 {
+		// empty file
+		doc.Kind = yaml.DocumentNode
+	}
+	if doc.Kind != yaml.DocumentNode {
+		return fmt.Errorf("not a YAML document")
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.MappingNode})
+	}
+	oncall, err := mappingValue(doc.Content[0], "oncall", yaml.MappingNode)
+	if err != nil {
+		return err
+	}
+	shortlist, err := mappingValue(oncall, "shortlist", yaml.SequenceNode)
+	if err != nil {
+		return fmt.Errorf("in `oncall`: %w", err)
+	}
+	for _, e := range entries {
+		n, err := shortlistEntryNode(e)
+		if err != nil {
+			return err
+		}
+		shortlist.Content = append(shortlist.Content, n)
+	}
+	return nil
+}
+
+// mappingValue returns the value of key in the mapping m, adding an empty
+// value of the given kind if the key is missing or null.
+func mappingValue(m *yaml.Node, key string, kind yaml.Kind) (*yaml.Node, error) {
+	if m.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping at line %d", m.Line)
+	}
+	for idx := 0; idx+1 < len(m.Content); idx += 2 {
+		if m.Content[idx].Value != key {
+			continue
+		}
+		v := m.Content[idx+1]
+		if v.Kind == yaml.ScalarNode && v.Tag == "!!null" {
+			v.Kind, v.Tag, v.Value = kind, "", ""
+		}
+		if v.Kind != kind {
+			return nil, fmt.Errorf("`%s` at line %d has an unexpected type", key, v.Line)
+		}
+		return v, nil
+	}
+	v := &yaml.Node{Kind: kind}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, v)
+	return v, nil
+}
+
+// mergeShortlistIntoConfig adds the entries to the shortlist of the config
+// file at path, after confirmation unless yes is set.
+func mergeShortlistIntoConfig(path string, entries []shortlistInitEntry, yes bool) error {
+	if path == "" {
+		return fmt.Errorf("no config file in use, cannot merge")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file %q: %w", path, err)
+	}
+	if err := mergeShortlistEntries(&doc, entries); err != nil {
+		return fmt.Errorf("failed to merge shortlist into %q: %w", path, err)
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if !yes {
+		ok, err := confirm(fmt.Sprintf("Do you want to add %d entries to the shortlist in %s? The file will be rewritten: comments are kept, but blank lines are dropped and it is re-indented", len(entries), path))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Printf("\nAborting\n")
+			return nil
+		}
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat config file: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), fi.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	fmt.Printf("Added %d entries to the shortlist in %s\n", len(entries), path)
+	return nil
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"gopkg.in/yaml.v3"
+)
+
+func TestProposeShortlistEntries(t *testing.T) {
+	sched := func(id, name string) pagerduty.Schedule {
+		return pagerduty.Schedule{APIObject: pagerduty.APIObject{ID: id}, Name: name}
+	}
+	schedules := []pagerduty.Schedule{
+		sched("P1", "Storage Team On-Call"),
+		sched("P2", "Kubernetes Platform - Primary"),
+		sched("P3", "Networking Oncall"),
+		sched("P4", "Databases"),
+	}
+	services := []pagerduty.Service{{APIObject: pagerduty.APIObject{ID: "S1"}, Name: "Billing API"}}
+	// P3 is pinned by an entry, P4 matched by the query of another one
+	coveredSchedules := map[string]struct{}{"P3": {}, "P4": {}}
+
+	var buf bytes.Buffer
+	if err := writeShortlistSnippet(&buf, proposeShortlistEntries(schedules, services, coveredSchedules, nil)); err != nil {
+		t.Fatalf("writeShortlistSnippet: %v", err)
+	}
+	want := `shortlist:
+  - name: Billing API
+    component: billing-api
+    aliases: [billing, api]
+    service_id: S1 # Billing API
+  - name: Kubernetes Platform
+    component: kubernetes-platform
+    aliases: [kubernetes, platform]
+    schedule_id: P2 # Kubernetes Platform - Primary
+  - name: Storage
+    component: storage
+    schedule_id: P1 # Storage Team On-Call
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMergeShortlistEntries(t *testing.T) {
+	entries := []shortlistInitEntry{{Name: "Storage", Component: "storage", ScheduleID: "P1", Source: "Storage Team On-Call"}}
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "existing shortlist",
+			in: `# my config
+oncall:
+  # curated entries
+  shortlist:
+    - name: Network
+      schedule_id: P3 # pinned
+timezone: UTC
+`,
+			want: `# my config
+oncall:
+  # curated entries
+  shortlist:
+    - name: Network
+      schedule_id: P3 # pinned
+    - name: Storage
+      component: storage
+      schedule_id: P1 # Storage Team On-Call
+timezone: UTC
+`,
+		},
+		{
+			name: "no shortlist",
+			in: `oncall:
+  default_query: sre
+`,
+			want: `oncall:
+  default_query: sre
+  shortlist:
+    - name: Storage
+      component: storage
+      schedule_id: P1 # Storage Team On-Call
+`,
+		},
+		{
+			name: "empty oncall",
+			in: `timezone: UTC # local
+oncall:
+`,
+			want: `timezone: UTC # local
+oncall:
+  shortlist:
+    - name: Storage
+      component: storage
+      schedule_id: P1 # Storage Team On-Call
+`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var doc yaml.Node
+			if err := yaml.Unmarshal([]byte(tc.in), &doc); err != nil {
+				t.Fatalf("yaml.Unmarshal: %v", err)
+			}
+			if err := mergeShortlistEntries(&doc, entries); err != nil {
+				t.Fatalf("mergeShortlistEntries: %v", err)
+			}
+			var buf bytes.Buffer
+			enc := yaml.NewEncoder(&buf)
+			enc.SetIndent(2)
+			if err := enc.Encode(&doc); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte("oncall:\n  shortlist: nope\n"), &doc); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	if err := mergeShortlistEntries(&doc, entries); err == nil || !strings.Contains(err.Error(), "unexpected type") {
+		t.Errorf("expected an error merging into a scalar shortlist, got %v", err)
+	}
+}
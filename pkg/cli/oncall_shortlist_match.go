@@ -1,6 +1,9 @@
 package cli
 
 import (
+	"fmt"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/insomniacslk/sre/pkg/config"
@@ -44,49 +47,236 @@ func expandSynonyms(query string, groups [][]string, caseSensitive bool) map[str
 	return out
 }
 
-// shortlistEntryMatches reports whether a shortlist entry matches any of the
-// (already synonym-expanded, normalized) query terms. Targets are the entry's
-// component, name and aliases. In exact mode a target must equal a query term;
-// otherwise a bidirectional substring match (fuzzy) is enough.
-func shortlistEntryMatches(e config.OncallShortlistEntry, queryTerms map[string]struct{}, exact, caseSensitive bool) bool {
+// Match scores, from the strongest to the weakest. Matches through a synonym
+// score scoreSynonymPenalty less than direct ones.
+const (
+	scoreExact          = 100
+	scoreTokenPrefix    = 80
+	scoreSubstring      = 60
+	scoreReverseSubstr  = 50
+	scoreFuzzy          = 40
+	scoreSynonymPenalty = 10
+)
+
+// minSubstringLength is the shortest query matched as a substring anywhere in
+// a target, so that short filters like "a" don't match everything. Shorter
+// queries still match whole terms and term prefixes. Targets of any length
+// still match when contained in the query, e.g. the alias "db" matches
+// "db-primary".
+const minSubstringLength = 3
+
+// shortlistTargets returns the terms an entry is matched against: its
+// component, name and aliases.
+func shortlistTargets(e config.OncallShortlistEntry) []string {
 	targets := make([]string, 0, 2+len(e.Aliases))
 	targets = append(targets, e.Component, e.Name)
 	targets = append(targets, e.Aliases...)
-	for _, t := range targets {
-		if strings.TrimSpace(t) == "" {
-			continue
+	return targets
+}
+
+// termTokens splits a term into its words, normalized, e.g.
+// "Kubernetes / Platform" into "kubernetes" and "platform".
+func termTokens(t string, caseSensitive bool) []string {
+	var tokens []string
+	for _, w := range strings.FieldsFunc(t, func(r rune) bool { return strings.ContainsRune("-_/. ", r) }) {
+		if nw := normalizeTerm(w, caseSensitive); nw != "" {
+			tokens = append(tokens, nw)
 		}
-		nt := normalizeTerm(t, caseSensitive)
-		for qt := range queryTerms {
-			if qt == "" {
-				continue
+	}
+	return tokens
+}
+
+// maxEditDistance is how many typos are tolerated in a query of n characters.
+func maxEditDistance(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b: the number of insertions, deletions, substitutions and transpositions of
+// adjacent characters needed to turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j] is the distance between the first i runes of a and the first
+	// j runes of b
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
 			}
-			if exact {
-				if nt == qt {
-					return true
-				}
-			} else if strings.Contains(nt, qt) || strings.Contains(qt, nt) {
-				return true
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
 			}
 		}
 	}
-	return false
+	return d[len(ra)][len(rb)]
 }
 
-// selectShortlistEntries returns the entries matching the query. An empty query
-// matches everything. Synonym groups (from `oncall.synonyms` in the config) let
-// a search term match entries tagged with an equivalent term.
+// termScore scores how well the normalized query term qt matches the target
+// t. In exact mode only equal terms match. In case-sensitive mode, terms
+// differing only by case never match, even as typos.
+func termScore(t, qt string, exact, caseSensitive bool) int {
+	nt := normalizeTerm(t, caseSensitive)
+	if nt == "" || qt == "" {
+		return 0
+	}
+	if nt == qt {
+		return scoreExact
+	}
+	if exact || (caseSensitive && strings.EqualFold(nt, qt)) {
+		return 0
+	}
+	tokens := termTokens(t, caseSensitive)
+	for _, tok := range append([]string{nt}, tokens...) {
+		if strings.HasPrefix(tok, qt) {
+			return scoreTokenPrefix
+		}
+	}
+	if len(qt) >= minSubstringLength && strings.Contains(nt, qt) {
+		return scoreSubstring
+	}
+	if nt != "" && strings.Contains(qt, nt) {
+		return scoreReverseSubstr
+	}
+	best := 0
+	if maxDist := maxEditDistance(len(qt)); maxDist > 0 {
+		for _, tok := range append([]string{nt}, tokens...) {
+			if dist := editDistance(tok, qt); dist <= maxDist {
+				best = max(best, scoreFuzzy-scoreSynonymPenalty*(dist-1))
+			}
+		}
+	}
+	return best
+}
+
+// shortlistEntryScore scores how well a shortlist entry matches the query, 0
+// meaning no match. queryTerms are the normalized query and its synonyms; the
+// best scoring target and term win, with matches through synonyms scoring a
+// bit less than direct ones.
+func shortlistEntryScore(e config.OncallShortlistEntry, query string, queryTerms map[string]struct{}, exact, caseSensitive bool) int {
+	nq := normalizeTerm(query, caseSensitive)
+	best := 0
+	for _, t := range shortlistTargets(e) {
+		for qt := range queryTerms {
+			score := termScore(t, qt, exact, caseSensitive)
+			if score > 0 && qt != nq {
+				score -= scoreSynonymPenalty
+			}
+			best = max(best, score)
+		}
+	}
+	return best
+}
+
+// selectShortlistEntries returns the entries matching the query, the best
+// matches first (entries matching equally well keep their configured order).
+// An empty query matches everything. Synonym groups (from `oncall.synonyms`
+// in the config) let a search term match entries tagged with an equivalent
+// term.
+//
+// Matching is scored: whole terms beat term prefixes, which beat substrings,
+// which beat typos (edit distance). In exact mode only whole terms match.
 func selectShortlistEntries(entries []config.OncallShortlistEntry, query string, synonyms [][]string, exact, caseSensitive bool) []config.OncallShortlistEntry {
 	if strings.TrimSpace(query) == "" {
 		return entries
 	}
 	queryTerms := expandSynonyms(query, synonyms, caseSensitive)
 
-	selected := make([]config.OncallShortlistEntry, 0, len(entries))
+	type scored struct {
+		entry config.OncallShortlistEntry
+		score int
+	}
+	var matches []scored
 	for _, e := range entries {
-		if shortlistEntryMatches(e, queryTerms, exact, caseSensitive) {
-			selected = append(selected, e)
+		if score := shortlistEntryScore(e, query, queryTerms, exact, caseSensitive); score > 0 {
+			matches = append(matches, scored{entry: e, score: score})
 		}
 	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	selected := make([]config.OncallShortlistEntry, 0, len(matches))
+	for _, m := range matches {
+		selected = append(selected, m.entry)
+	}
 	return selected
 }
+
+// maxSuggestions is how many components are suggested when nothing matches.
+const maxSuggestions = 3
+
+// suggestShortlistTerms returns the components (or names, for entries
+// without one) of the entries closest to the query, to suggest when nothing
+// matches it. Only reasonably close entries are suggested.
+func suggestShortlistTerms(entries []config.OncallShortlistEntry, query string, caseSensitive bool) []string {
+	nq := normalizeTerm(query, caseSensitive)
+	if nq == "" {
+		return nil
+	}
+	maxDist := max(2, len([]rune(nq))/2)
+	type suggestion struct {
+		term string
+		dist int
+	}
+	var suggestions []suggestion
+	for _, e := range entries {
+		best := -1
+		for _, t := range shortlistTargets(e) {
+			for _, tok := range append([]string{normalizeTerm(t, caseSensitive)}, termTokens(t, caseSensitive)...) {
+				if tok == "" {
+					continue
+				}
+				if dist := editDistance(tok, nq); dist <= maxDist && (best < 0 || dist < best) {
+					best = dist
+				}
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		term := e.Component
+		if term == "" {
+			term = e.Name
+		}
+		suggestions = append(suggestions, suggestion{term: term, dist: best})
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].dist < suggestions[j].dist
+	})
+	var out []string
+	for _, s := range suggestions {
+		if len(out) == maxSuggestions {
+			break
+		}
+		if !slices.Contains(out, s.term) {
+			out = append(out, s.term)
+		}
+	}
+	return out
+}
+
+// didYouMean returns a hint suggesting the shortlist components closest to a
+// query matching no entry, or an empty string if none is close enough.
+func didYouMean(entries []config.OncallShortlistEntry, query string, caseSensitive bool) string {
+	suggestions := suggestShortlistTerms(entries, query, caseSensitive)
+	if len(suggestions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %s?", strings.Join(suggestions, ", "))
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/insomniacslk/sre/pkg/config"
@@ -34,6 +35,7 @@ func TestSelectShortlistEntries(t *testing.T) {
 		{name: "substring fuzzy sec", query: "sec", wantComps: []string{"security"}},
 		{name: "alias match db", query: "db", wantComps: []string{"data"}},
 		{name: "alias match database", query: "database", wantComps: []string{"data"}},
+		{name: "short alias contained in query", query: "db-primary", wantComps: []string{"data"}},
 		{name: "exact rejects substring", query: "sec", exact: true, wantComps: nil},
 		{name: "exact accepts full term", query: "security", exact: true, wantComps: []string{"security"}},
 		{name: "exact still honors synonyms", query: "k8s", synonyms: syn, exact: true, wantComps: []string{"kubernetes"}},
@@ -71,3 +73,79 @@ func TestSelectShortlistEntriesConfiguredSynonyms(t *testing.T) {
 		t.Fatalf("configured synonym: got %v, want [storage]", got)
 	}
 }
+
+func TestSelectShortlistEntriesRanking(t *testing.T) {
+	entries := []config.OncallShortlistEntry{
+		{Name: "Data platform", Component: "data-platform"},
+		{Name: "Kubernetes / Platform", Component: "kubernetes"},
+		{Name: "Platform", Component: "platform"},
+		{Name: "Storage", Component: "storage", Aliases: []string{"disk"}},
+	}
+	syn := [][]string{{"k8s", "kubernetes"}}
+	tests := []struct {
+		name          string
+		query         string
+		caseSensitive bool
+		wantComps     []string
+	}{
+		{name: "exact match first", query: "platform", wantComps: []string{"platform", "data-platform", "kubernetes"}},
+		{name: "typo", query: "kubernets", wantComps: []string{"kubernetes"}},
+		{name: "transposition", query: "stroage", wantComps: []string{"storage"}},
+		{name: "direct match beats synonym", query: "kubernetes", wantComps: []string{"kubernetes"}},
+		{name: "short filters only match word prefixes", query: "ta", wantComps: nil},
+		{name: "short prefix", query: "st", wantComps: []string{"storage"}},
+		{name: "case-sensitive rejects case-only typos", query: "STORAGE", caseSensitive: true, wantComps: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := selectShortlistEntries(entries, tc.query, syn, false, tc.caseSensitive)
+			var gotComps []string
+			for _, e := range got {
+				gotComps = append(gotComps, e.Component)
+			}
+			if strings.Join(gotComps, ",") != strings.Join(tc.wantComps, ",") {
+				t.Fatalf("query %q: got %v, want %v", tc.query, gotComps, tc.wantComps)
+			}
+		})
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	entries := []config.OncallShortlistEntry{
+		{Name: "Kubernetes", Component: "kubernetes"},
+		{Name: "Networking", Component: "network"},
+		{Name: "Storage", Component: "storage"},
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "netwrok-team", want: ", did you mean network?"},
+		{query: "kubrnts", want: ", did you mean kubernetes?"},
+		{query: "sotrge", want: ", did you mean storage?"},
+		{query: "xyz", want: ""},
+	}
+	for _, tc := range tests {
+		if got := didYouMean(entries, tc.query, false); got != tc.want {
+			t.Errorf("didYouMean(%q) = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kubernetes", "kubernets", 1},
+		{"storage", "stroage", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tc := range tests {
+		if got := editDistance(tc.a, tc.b); got != tc.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/ansi"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var flagOncallSwapYes bool
+
+func init() {
+	OncallCmd.AddCommand(OncallSwapCmd)
+	OncallSwapCmd.Flags().BoolVarP(&flagOncallSwapYes, "yes", "y", false, "Do not ask for confirmation before creating the overrides")
+}
+
+var OncallSwapCmd = &cobra.Command{
+	Use:     "swap <userA> <shiftA-time> <userB> <shiftB-time> [schedule]",
+	Aliases: []string{"sw"},
+	Short:   "Swap two users' shifts by creating a pair of overrides (PagerDuty)",
+	Long: `Swap two users' shifts in an oncall schedule.
+
+The shift of <userA> containing <shiftA-time> and the shift of <userB>
+containing <shiftB-time> are located in the rendered final schedule, then two
+overrides are created: <userB> covers the first shift and <userA> covers the
+second one. If the second override cannot be created, the first one is deleted
+so the schedule is left untouched.
+
+Times accept the same formats as the ` + "`override`" + ` subcommand. If [schedule] is
+not specified, ` + "`oncall.default_schedule`" + ` from the config is used.`,
+	Args: cobra.RangeArgs(4, 5),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logrus.Debugf("Running oncall swap command")
+		ctx := context.Background()
+		cfg, err := GetConfig()
+		if err != nil {
+			return err
+		}
+
+		timeA, err := parseOverrideTimeString(args[1])
+		if err != nil {
+			return fmt.Errorf("failed to parse time of the first shift: %w", err)
+		}
+		timeB, err := parseOverrideTimeString(args[3])
+		if err != nil {
+			return fmt.Errorf("failed to parse time of the second shift: %w", err)
+		}
+		scheduleID := cfg.Oncall.DefaultSchedule
+		if len(args) > 4 {
+			scheduleID = args[4]
+		}
+		if scheduleID == "" {
+			return fmt.Errorf("no schedule ID specified")
+		}
+
+		client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+		scheduleID, err = resolveScheduleID(ctx, client, scheduleID)
+		if err != nil {
+			return err
+		}
+		userA, err := lookupUser(ctx, client, args[0], true)
+		if err != nil {
+			return err
+		}
+		userB, err := lookupUser(ctx, client, args[2], true)
+		if err != nil {
+			return err
+		}
+		if userA.ID == userB.ID {
+			return fmt.Errorf("cannot swap shifts of %s <%s> with themselves", userA.Name, userA.Email)
+		}
+
+		since, until := *timeA, *timeB
+		if until.Before(since) {
+			since, until = until, since
+		}
+		sched, segments, err := fetchSchedule(ctx, client, scheduleID, since.Add(-shiftSearchWindow), until.Add(shiftSearchWindow), cfg.Timezone)
+		if err != nil {
+			return err
+		}
+		shiftA, err := findUserShift(segments, userA, *timeA)
+		if err != nil {
+			return err
+		}
+		shiftB, err := findUserShift(segments, userB, *timeB)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\n", ansi.Bold(sched.Name))
+		fmt.Printf("    Summary: %s\n", ansi.ToURL(sched.Summary, sched.HTMLURL))
+		fmt.Println()
+		fmt.Printf("%s:\n", ansi.Bold("Before"))
+		fmt.Printf("    %s\t%s\n", shiftA, ansi.ToURL(userA.Name, userA.HTMLURL))
+		fmt.Printf("    %s\t%s\n", shiftB, ansi.ToURL(userB.Name, userB.HTMLURL))
+		fmt.Printf("%s:\n", ansi.Bold("After"))
+		fmt.Printf("    %s\t%s\n", shiftA, ansi.ToURL(userB.Name, userB.HTMLURL))
+		fmt.Printf("    %s\t%s\n", shiftB, ansi.ToURL(userA.Name, userA.HTMLURL))
+		fmt.Println()
+
+		if !flagOncallSwapYes {
+			ok, err := confirm(fmt.Sprintf("Do you want to swap the above shifts of %s and %s?", userA.Name, userB.Name))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Printf("\nAborting\n")
+				return nil
+			}
+		}
+
+		first, err := client.CreateOverrideWithContext(ctx, scheduleID, pagerduty.Override{
+			User:  userB.APIObject,
+			Start: shiftA.Start.Format(time.RFC3339),
+			End:   shiftA.End.Format(time.RFC3339),
+		})
+		if err != nil {
+			return fmt.Errorf("override creation for %s failed, nothing was changed: %w", userB.Name, err)
+		}
+		_, err = client.CreateOverrideWithContext(ctx, scheduleID, pagerduty.Override{
+			User:  userA.APIObject,
+			Start: shiftB.Start.Format(time.RFC3339),
+			End:   shiftB.End.Format(time.RFC3339),
+		})
+		if err != nil {
+			if rerr := client.DeleteOverrideWithContext(ctx, scheduleID, first.ID); rerr != nil {
+				return fmt.Errorf("override creation for %s failed: %w; rolling back override %s also failed, delete it manually: %v", userA.Name, err, first.ID, rerr)
+			}
+			return fmt.Errorf("override creation for %s failed, rolled back the override for %s: %w", userA.Name, userB.Name, err)
+		}
+		fmt.Printf("Shifts swapped\n")
+		return nil
+	},
+}
+
+// findUserShift returns the segment containing t, and fails if nobody or
+// somebody other than user is on call at that time.
+func findUserShift(segments []scheduleSegment, user *pagerduty.User, t time.Time) (*scheduleSegment, error) {
+	seg := segmentAt(segments, t)
+	if seg == nil {
+		return nil, fmt.Errorf("nobody is oncall at %s", t.Format(shiftTimeFormat))
+	}
+	if seg.User.ID != user.ID {
+		return nil, fmt.Errorf("%s <%s> is not oncall at %s (%s is)", user.Name, user.Email, t.Format(shiftTimeFormat), seg.User.Summary)
+	}
+	return seg, nil
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/insomniacslk/sre/pkg/ansi"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagOncallUserAuditTeams  []string
+	flagOncallUserAuditStrict bool
+)
+
+func init() {
+	OncallUserCmd.AddCommand(OncallUserAuditCmd)
+	OncallUserAuditCmd.Flags().StringSliceVarP(&flagOncallUserAuditTeams, "team", "t", nil, "Team to audit, either a name query or a team ID prefixed by \"+\", can be repeated (default: the teams in pagerduty.teams)")
+	OncallUserAuditCmd.Flags().BoolVar(&flagOncallUserAuditStrict, "strict", false, "Exit with an error on warnings too, not only on errors")
+}
+
+var OncallUserAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check that every member of the configured teams can be reached when paged (PagerDuty)",
+	Long: `Check the contact methods and notification rules of every member of the
+teams in ` + "`pagerduty.teams`" + ` (or passed via --team), and report:
+
+  * users without a phone or SMS contact method (error)
+  * users without high-urgency notification rules, or whose high-urgency
+    rules only send e-mails (error)
+  * blacklisted contact methods (error)
+  * users without the PagerDuty mobile app, i.e. no push contact method
+    (warning)
+  * SMS contact methods that are not enabled for SMS (warning). The
+    PagerDuty API does not say whether a contact method is verified, so
+    this is only an approximation: an unverified number is not enabled
+    for SMS, but a number can also be disabled for other reasons
+
+The command exits with an error if any error is found, or any warning with
+--strict, so it can be used in CI.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logrus.Debugf("Running oncall user audit command")
+		ctx := context.Background()
+		cfg, err := GetConfig()
+		if err != nil {
+			return err
+		}
+		teams := cfg.PagerDuty.Teams
+		if len(flagOncallUserAuditTeams) > 0 {
+			teams = flagOncallUserAuditTeams
+		}
+		client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+		teamIDs, err := resolveTeamIDs(ctx, client, teams)
+		if err != nil {
+			return err
+		}
+
+		users, err := listTeamUsers(ctx, client, teamIDs)
+		if err != nil {
+			return err
+		}
+
+		var findings []auditFinding
+		for _, u := range users {
+			findings = append(findings, auditUser(u)...)
+		}
+		fmt.Printf("Audited %d users in %d teams\n\n", len(users), len(teamIDs))
+		failOn := auditError
+		if flagOncallUserAuditStrict {
+			failOn = auditWarning
+		}
+		if err := printAuditFindings(findings, failOn); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		return nil
+	},
+}
+
+// auditUser returns the findings of the reachability audit of a user. The
+// user must include its contact methods and notification rules.
+func auditUser(u *pagerduty.User) []auditFinding {
+	var findings []auditFinding
+	add := func(sev auditSeverity, format string, a ...interface{}) {
+		findings = append(findings, auditFinding{
+			Severity: sev,
+			Subject:  ansi.ToURL(u.Name, u.HTMLURL),
+			Message:  fmt.Sprintf(format, a...),
+		})
+	}
+
+	var hasPhone, hasPush bool
+	for _, m := range u.ContactMethods {
+		switch contactMethodType(m) {
+		case "phone_contact_method":
+			hasPhone = true
+		case "sms_contact_method":
+			hasPhone = true
+			if !m.Enabled {
+				add(auditWarning, "SMS contact method %q is not enabled for SMS, it may be unverified", m.Label)
+			}
+		case "push_notification_contact_method":
+			hasPush = true
+		}
+		if m.Blacklisted {
+			add(auditError, "contact method %q (%s) is blacklisted", m.Label, m.Address)
+		}
+	}
+	if !hasPhone {
+		add(auditError, "has no phone or SMS contact method")
+	}
+	if !hasPush {
+		add(auditWarning, "has no push contact method, the PagerDuty app is not installed")
+	}
+
+	var highUrgency, highUrgencyEmail int
+	for _, r := range u.NotificationRules {
+		if r.Urgency != "high" {
+			continue
+		}
+		highUrgency++
+		if contactMethodType(r.ContactMethod) == "email_contact_method" {
+			highUrgencyEmail++
+		}
+	}
+	switch {
+	case highUrgency == 0:
+		add(auditError, "has no high-urgency notification rules")
+	case highUrgency == highUrgencyEmail:
+		add(auditError, "high-urgency notification rules only send e-mails")
+	}
+	return findings
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestAuditUser(t *testing.T) {
+	email := pagerduty.ContactMethod{Type: "email_contact_method_reference", Label: "Work", Address: "alice@example.com"}
+	phone := pagerduty.ContactMethod{Type: "phone_contact_method", Label: "Mobile", Address: "5555555"}
+	sms := pagerduty.ContactMethod{Type: "sms_contact_method", Label: "Mobile", Address: "5555555", Enabled: true}
+	push := pagerduty.ContactMethod{Type: "push_notification_contact_method", Label: "iPhone"}
+	rule := func(m pagerduty.ContactMethod, urgency string) pagerduty.NotificationRule {
+		return pagerduty.NotificationRule{ContactMethod: m, Urgency: urgency}
+	}
+
+	tests := []struct {
+		name string
+		user pagerduty.User
+		want []string
+	}{
+		{
+			name: "reachable",
+			user: pagerduty.User{
+				ContactMethods:    []pagerduty.ContactMethod{email, phone, sms, push},
+				NotificationRules: []pagerduty.NotificationRule{rule(email, "high"), rule(push, "high"), rule(email, "low")},
+			},
+		},
+		{
+			name: "email only",
+			user: pagerduty.User{
+				ContactMethods:    []pagerduty.ContactMethod{email},
+				NotificationRules: []pagerduty.NotificationRule{rule(email, "high"), rule(email, "low")},
+			},
+			want: []string{
+				"ERROR has no phone or SMS contact method",
+				"ERROR high-urgency notification rules only send e-mails",
+				"WARNING has no push contact method, the PagerDuty app is not installed",
+			},
+		},
+		{
+			name: "broken methods and no high urgency rules",
+			user: pagerduty.User{
+				ContactMethods: []pagerduty.ContactMethod{
+					{Type: "phone_contact_method", Label: "Old", Address: "1234567", Blacklisted: true},
+					{Type: "sms_contact_method", Label: "Mobile"},
+					push,
+				},
+				NotificationRules: []pagerduty.NotificationRule{rule(push, "low")},
+			},
+			want: []string{
+				`ERROR contact method "Old" (1234567) is blacklisted`,
+				"ERROR has no high-urgency notification rules",
+				`WARNING SMS contact method "Mobile" is not enabled for SMS, it may be unverified`,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, f := range auditUser(&tc.user) {
+				got = append(got, f.Severity.String()+" "+f.Message)
+			}
+			sort.Strings(got)
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Fatalf("got findings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/PagerDuty/go-pagerduty"
 	"github.com/insomniacslk/sre/pkg/ansi"
@@ -11,15 +12,21 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var flagOncallUserAll bool
+
 func init() {
 	OncallCmd.AddCommand(OncallUserCmd)
+	OncallUserCmd.Flags().BoolVarP(&flagOncallUserAll, "all", "a", false, "Show all the users matching the query instead of picking one")
 }
 
 var OncallUserCmd = &cobra.Command{
 	Use:     "user",
 	Aliases: []string{"u"},
 	Short:   "Show user information",
-	Args:    cobra.MinimumNArgs(1),
+	Long: `Show user information: teams, contact methods, notification rules per
+urgency, escalation policies, schedules with shifts in the next 90 days, the
+current and next shift, and the incidents acknowledged in the last 7 days.`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		logrus.Debugf("Running user command")
 		ctx := context.Background()
@@ -27,6 +34,10 @@ var OncallUserCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return fmt.Errorf("cannot load timezone %q: %w", cfg.Timezone, err)
+		}
 		client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
 
 		// search for a user
@@ -34,13 +45,25 @@ var OncallUserCmd = &cobra.Command{
 		separator := strings.Repeat("-", 80)
 		opts := pagerduty.ListUsersOptions{
 			Query:    query,
-			Includes: []string{"contact_methods"},
+			Includes: []string{"contact_methods", "notification_rules"},
 		}
 		resp, err := client.ListUsersWithContext(ctx, opts)
 		if err != nil {
 			logrus.Fatalf("Failed to list users: %v", err)
 		}
-		for _, u := range resp.Users {
+		users := resp.Users
+		if len(users) > 1 && !flagOncallUserAll {
+			items := make([]string, 0, len(users))
+			for _, u := range users {
+				items = append(items, fmt.Sprintf("%s <%s> %s", u.Name, u.Email, u.JobTitle))
+			}
+			idx, err := pick(fmt.Sprintf("users matching %q", query), items)
+			if err != nil {
+				return err
+			}
+			users = users[idx : idx+1]
+		}
+		for _, u := range users {
 			fmt.Printf(ansi.Bold("Name      :")+" %s\n", u.Name)
 			fmt.Printf(ansi.Bold("Email     :")+" %s\n", ansi.ToURL(u.Email, "mailto:"+u.Email))
 			fmt.Printf(ansi.Bold("Title     :")+" %s\n", u.JobTitle)
@@ -66,6 +89,14 @@ var OncallUserCmd = &cobra.Command{
 					fmt.Printf("  %s: %s (%s)\n", c.Address, c.Type, c.Summary)
 				}
 			}
+			fmt.Print(ansi.Bold("Notify    :\n"))
+			printNotificationRules(&u)
+			if err := printUserOncalls(ctx, client, &u, loc); err != nil {
+				return err
+			}
+			if err := printUserAcknowledgements(ctx, client, &u, loc); err != nil {
+				return err
+			}
 			fmt.Println(separator)
 		}
 		return nil
@@ -0,0 +1,269 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/ansi"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+const (
+	// userShiftsHorizon is how far in the future the user command looks for
+	// the shifts of a user.
+	userShiftsHorizon = 90 * 24 * time.Hour
+	// userAcknowledgementsWindow is how far in the past the user command
+	// looks for incidents acknowledged by a user.
+	userAcknowledgementsWindow = 7 * 24 * time.Hour
+)
+
+// describeContactMethod returns the kind of a contact method followed by its
+// label, e.g. "Phone (Mobile)".
+func describeContactMethod(c pagerduty.ContactMethod) string {
+	kind := c.Type
+	switch contactMethodType(c) {
+	case "email_contact_method":
+		kind = "E-mail"
+	case "phone_contact_method":
+		kind = "Phone"
+	case "push_notification_contact_method":
+		kind = "Push"
+	case "sms_contact_method":
+		kind = "SMS"
+	}
+	label := c.Label
+	if label == "" {
+		label = c.Summary
+	}
+	return fmt.Sprintf("%s (%s)", kind, label)
+}
+
+// printNotificationRules prints the notification rules of the user, grouped
+// by urgency and sorted by delay.
+func printNotificationRules(u *pagerduty.User) {
+	methods := make(map[string]pagerduty.ContactMethod, len(u.ContactMethods))
+	for _, c := range u.ContactMethods {
+		methods[c.ID] = c
+	}
+	rules := append([]pagerduty.NotificationRule(nil), u.NotificationRules...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Urgency != rules[j].Urgency {
+			// "high" before "low"
+			return rules[i].Urgency < rules[j].Urgency
+		}
+		return rules[i].StartDelayInMinutes < rules[j].StartDelayInMinutes
+	})
+	if len(rules) == 0 {
+		fmt.Printf("  none\n")
+	}
+	urgency := ""
+	for _, r := range rules {
+		if r.Urgency != urgency {
+			urgency = r.Urgency
+			fmt.Printf("  %s urgency:\n", urgency)
+		}
+		method, ok := methods[r.ContactMethod.ID]
+		if !ok {
+			method = r.ContactMethod
+		}
+		when := "immediately"
+		if r.StartDelayInMinutes > 0 {
+			when = fmt.Sprintf("after %d min", r.StartDelayInMinutes)
+		}
+		fmt.Printf("    %-12s %s\n", when+":", describeContactMethod(method))
+	}
+}
+
+// printUserOncalls prints the escalation policies and schedules the user
+// belongs to, and their current and next shifts.
+func printUserOncalls(ctx context.Context, client *pagerduty.Client, u *pagerduty.User, loc *time.Location) error {
+	direct, err := listEscalationPolicies(ctx, client, pagerduty.ListEscalationPoliciesOptions{UserIDs: []string{u.ID}})
+	if err != nil {
+		return fmt.Errorf("failed to get escalation policies of %s: %w", u.Name, err)
+	}
+	allSchedules, err := listSchedules(ctx, client, pagerduty.ListSchedulesOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list schedules: %w", err)
+	}
+	schedules := userSchedules(allSchedules, u.ID)
+	now := time.Now()
+	oncalls, err := listOnCalls(ctx, client, pagerduty.ListOnCallOptions{
+		UserIDs: []string{u.ID},
+		Since:   now.Format(time.RFC3339),
+		Until:   now.Add(userShiftsHorizon).Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get oncalls of %s: %w", u.Name, err)
+	}
+
+	fmt.Print(ansi.Bold("Policies  :\n"))
+	for _, ep := range userPolicies(direct, schedules, oncalls) {
+		fmt.Printf("  %s\n", ansi.ToURL(ep.Summary, ep.HTMLURL))
+	}
+	fmt.Print(ansi.Bold("Schedules :\n"))
+	for _, s := range schedules {
+		fmt.Printf("  %s\n", ansi.ToURL(s.Name, s.HTMLURL))
+	}
+	fmt.Print(ansi.Bold("Oncall    :\n"))
+	current, next := userShifts(oncalls, now)
+	for _, oc := range current {
+		end, _ := time.Parse(time.RFC3339, oc.End)
+		fmt.Printf("  now, in %s until %s\n", oc.Schedule.Summary, end.In(loc).Format(shiftTimeFormat))
+	}
+	if next != nil {
+		start, _ := time.Parse(time.RFC3339, next.Start)
+		end, _ := time.Parse(time.RFC3339, next.End)
+		fmt.Printf("  next, in %s from %s to %s\n", next.Schedule.Summary, start.In(loc).Format(shiftTimeFormat), end.In(loc).Format(shiftTimeFormat))
+	} else if len(current) == 0 {
+		fmt.Printf("  no shifts in the next %d days\n", int(userShiftsHorizon.Hours()/24))
+	}
+	return nil
+}
+
+// userSchedules returns the schedules the user is in, i.e. whose layers
+// include the user.
+func userSchedules(schedules []pagerduty.Schedule, userID string) []pagerduty.Schedule {
+	var out []pagerduty.Schedule
+	for _, s := range schedules {
+		for _, u := range s.Users {
+			if u.ID == userID {
+				out = append(out, s)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// userPolicies returns the escalation policies that can page the user, once
+// each: the ones targeting the user directly, the ones targeting the user's
+// schedules, and the ones the user is oncall for.
+func userPolicies(direct []pagerduty.EscalationPolicy, schedules []pagerduty.Schedule, oncalls []pagerduty.OnCall) []pagerduty.APIObject {
+	var out []pagerduty.APIObject
+	seen := make(map[string]struct{})
+	add := func(ep pagerduty.APIObject) {
+		if _, ok := seen[ep.ID]; ok || ep.ID == "" {
+			return
+		}
+		seen[ep.ID] = struct{}{}
+		out = append(out, ep)
+	}
+	for _, ep := range direct {
+		add(pagerduty.APIObject{ID: ep.ID, Summary: ep.Name, HTMLURL: ep.HTMLURL})
+	}
+	for _, s := range schedules {
+		for _, ep := range s.EscalationPolicies {
+			add(ep)
+		}
+	}
+	for _, oc := range oncalls {
+		add(oc.EscalationPolicy.APIObject)
+	}
+	return out
+}
+
+// userShifts returns the schedule shifts of the user in progress at the given
+// time, and the first one starting after it. Oncall entries for users
+// targeted directly by escalation policies are ignored, and shifts reached
+// through several policies are only returned once.
+func userShifts(oncalls []pagerduty.OnCall, now time.Time) ([]pagerduty.OnCall, *pagerduty.OnCall) {
+	var (
+		current   []pagerduty.OnCall
+		next      *pagerduty.OnCall
+		nextStart time.Time
+	)
+	seen := make(map[string]struct{})
+	for idx, oc := range oncalls {
+		if oc.Schedule.ID == "" {
+			continue
+		}
+		start, err := time.Parse(time.RFC3339, oc.Start)
+		if err != nil {
+			continue
+		}
+		if !start.After(now) {
+			key := oc.Schedule.ID + oc.Start
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				current = append(current, oc)
+			}
+			continue
+		}
+		if next == nil || start.Before(nextStart) {
+			next, nextStart = &oncalls[idx], start
+		}
+	}
+	return current, next
+}
+
+// printUserAcknowledgements prints the incidents acknowledged by the user
+// recently, looking at the log entries of the user's teams. Users without
+// teams are skipped, since that would mean scanning the log entries of the
+// whole account.
+func printUserAcknowledgements(ctx context.Context, client *pagerduty.Client, u *pagerduty.User, loc *time.Location) error {
+	header := ansi.Bold(fmt.Sprintf("Acked %dd  :\n", int(userAcknowledgementsWindow.Hours()/24)))
+	if len(u.Teams) == 0 {
+		fmt.Print(header)
+		fmt.Printf("  unknown, %s is not in any team\n", u.Name)
+		return nil
+	}
+	opts := pagerduty.ListLogEntriesOptions{
+		Limit:      100, // 100 is the maximum allowed by PagerDuty's API
+		Since:      time.Now().Add(-userAcknowledgementsWindow).Format(time.RFC3339),
+		IsOverview: true,
+	}
+	for _, t := range u.Teams {
+		opts.TeamIDs = append(opts.TeamIDs, t.ID)
+	}
+	var entries []pagerduty.LogEntry
+	for {
+		resp, err := client.ListLogEntriesWithContext(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("failed to get log entries: %w", err)
+		}
+		entries = append(entries, resp.LogEntries...)
+		if !resp.More || len(resp.LogEntries) == 0 {
+			break
+		}
+		opts.Offset += uint(len(resp.LogEntries))
+	}
+	acks := userAcknowledgements(entries, u.ID)
+	fmt.Print(header)
+	if len(acks) == 0 {
+		fmt.Printf("  none\n")
+	}
+	for _, e := range acks {
+		at, _ := time.Parse(time.RFC3339, e.CreatedAt)
+		fmt.Printf("  %s  %s\n", at.In(loc).Format(shiftTimeFormat), ansi.ToURL(e.Incident.Summary, e.Incident.HTMLURL))
+	}
+	return nil
+}
+
+// userAcknowledgements returns the acknowledgements made by the user among
+// the log entries, once per incident and most recent first.
+func userAcknowledgements(entries []pagerduty.LogEntry, userID string) []pagerduty.LogEntry {
+	var acks []pagerduty.LogEntry
+	for _, e := range entries {
+		if strings.HasPrefix(e.Type, "acknowledge_log_entry") && e.Agent.ID == userID {
+			acks = append(acks, e)
+		}
+	}
+	sort.SliceStable(acks, func(i, j int) bool {
+		// RFC3339 timestamps in UTC sort lexicographically
+		return acks[i].CreatedAt > acks[j].CreatedAt
+	})
+	out := acks[:0]
+	seen := make(map[string]struct{})
+	for _, e := range acks {
+		if _, ok := seen[e.Incident.ID]; ok {
+			continue
+		}
+		seen[e.Incident.ID] = struct{}{}
+		out = append(out, e)
+	}
+	return out
+}
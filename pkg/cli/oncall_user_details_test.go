@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestUserShifts(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	primary := pagerduty.Schedule{APIObject: pagerduty.APIObject{ID: "PSCHED1"}}
+	secondary := pagerduty.Schedule{APIObject: pagerduty.APIObject{ID: "PSCHED2"}}
+	oncalls := []pagerduty.OnCall{
+		// directly targeted by a policy, no shift
+		{EscalationLevel: 2},
+		{Schedule: primary, EscalationLevel: 1, Start: "2026-10-19T09:00:00Z", End: "2026-10-19T18:00:00Z"},
+		// same shift, reached through another policy
+		{Schedule: primary, EscalationLevel: 2, Start: "2026-10-19T09:00:00Z", End: "2026-10-19T18:00:00Z"},
+		{Schedule: secondary, EscalationLevel: 1, Start: "2026-10-26T09:00:00Z", End: "2026-10-27T09:00:00Z"},
+		{Schedule: primary, EscalationLevel: 1, Start: "2026-10-21T09:00:00Z", End: "2026-10-21T18:00:00Z"},
+	}
+	current, next := userShifts(oncalls, now)
+	if len(current) != 1 || current[0].Schedule.ID != "PSCHED1" {
+		t.Fatalf("got current shifts %+v, want only the PSCHED1 one", current)
+	}
+	if next == nil || next.Start != "2026-10-21T09:00:00Z" {
+		t.Fatalf("got next shift %+v, want the one starting on 2026-10-21", next)
+	}
+	if _, next := userShifts(oncalls[:3], now); next != nil {
+		t.Fatalf("got next shift %+v, want none", next)
+	}
+}
+
+func TestUserSchedulesAndPolicies(t *testing.T) {
+	obj := func(id string) pagerduty.APIObject { return pagerduty.APIObject{ID: id, Summary: id} }
+	schedules := []pagerduty.Schedule{
+		{APIObject: obj("PSCHED1"), Users: []pagerduty.APIObject{obj("PUSER01"), obj("PUSER02")}, EscalationPolicies: []pagerduty.APIObject{obj("PEP1")}},
+		{APIObject: obj("PSCHED2"), Users: []pagerduty.APIObject{obj("PUSER02")}, EscalationPolicies: []pagerduty.APIObject{obj("PEP2")}},
+		// no shift in the horizon, but the user is in its layers
+		{APIObject: obj("PSCHED3"), Users: []pagerduty.APIObject{obj("PUSER01")}, EscalationPolicies: []pagerduty.APIObject{obj("PEP3"), obj("PEP1")}},
+	}
+	mine := userSchedules(schedules, "PUSER01")
+	var got []string
+	for _, s := range mine {
+		got = append(got, s.ID)
+	}
+	if strings.Join(got, ",") != "PSCHED1,PSCHED3" {
+		t.Fatalf("got schedules %v, want [PSCHED1 PSCHED3]", got)
+	}
+
+	direct := []pagerduty.EscalationPolicy{{APIObject: pagerduty.APIObject{ID: "PEP4"}, Name: "Direct"}}
+	oncalls := []pagerduty.OnCall{
+		{EscalationPolicy: pagerduty.EscalationPolicy{APIObject: obj("PEP1")}},
+		{EscalationPolicy: pagerduty.EscalationPolicy{APIObject: obj("PEP5")}},
+	}
+	got = nil
+	for _, ep := range userPolicies(direct, mine, oncalls) {
+		got = append(got, ep.ID+"="+ep.Summary)
+	}
+	if want := "PEP4=Direct,PEP1=PEP1,PEP3=PEP3,PEP5=PEP5"; strings.Join(got, ",") != want {
+		t.Fatalf("got policies %v, want %s", got, want)
+	}
+}
+
+func TestUserAcknowledgements(t *testing.T) {
+	entry := func(typ, userID, incidentID, at string) pagerduty.LogEntry {
+		e := pagerduty.LogEntry{Incident: pagerduty.Incident{APIObject: pagerduty.APIObject{ID: incidentID}}}
+		e.Type = typ
+		e.Agent = pagerduty.Agent{ID: userID}
+		e.CreatedAt = at
+		return e
+	}
+	entries := []pagerduty.LogEntry{
+		entry("trigger_log_entry", "", "PINC001", "2026-10-18T10:00:00Z"),
+		entry("acknowledge_log_entry", "PUSER01", "PINC001", "2026-10-18T10:05:00Z"),
+		// acknowledged again after the timeout
+		entry("acknowledge_log_entry", "PUSER01", "PINC001", "2026-10-18T10:40:00Z"),
+		entry("acknowledge_log_entry", "PUSER02", "PINC002", "2026-10-18T11:00:00Z"),
+		entry("acknowledge_log_entry_reference", "PUSER01", "PINC003", "2026-10-19T08:00:00Z"),
+		entry("resolve_log_entry", "PUSER01", "PINC003", "2026-10-19T09:00:00Z"),
+	}
+	acks := userAcknowledgements(entries, "PUSER01")
+	if len(acks) != 2 {
+		t.Fatalf("got %d acknowledgements, want 2", len(acks))
+	}
+	if acks[0].Incident.ID != "PINC003" || acks[1].Incident.ID != "PINC001" {
+		t.Fatalf("got incidents %s, %s; want PINC003, PINC001", acks[0].Incident.ID, acks[1].Incident.ID)
+	}
+	if acks[1].CreatedAt != "2026-10-18T10:40:00Z" {
+		t.Fatalf("got acknowledgement at %s for PINC001, want the latest one", acks[1].CreatedAt)
+	}
+}
@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagOncallUserExportVCard  bool
+	flagOncallUserExportTeams  []string
+	flagOncallUserExportOutput string
+)
+
+func init() {
+	OncallUserCmd.AddCommand(OncallUserExportCmd)
+	OncallUserExportCmd.Flags().BoolVar(&flagOncallUserExportVCard, "vcard", false, "Export the contacts as vCards")
+	OncallUserExportCmd.Flags().StringSliceVarP(&flagOncallUserExportTeams, "team", "t", nil, "Team to export, either a name query or a team ID prefixed by \"+\", can be repeated (default: the teams in pagerduty.teams)")
+	OncallUserExportCmd.Flags().StringVarP(&flagOncallUserExportOutput, "output", "o", "", "File to write the contacts to (default: standard output)")
+}
+
+var OncallUserExportCmd = &cobra.Command{
+	Use:   "export --vcard",
+	Short: "Export the contacts of the members of the configured teams (PagerDuty)",
+	Long: `Export the contacts of every member of the teams in ` + "`pagerduty.teams`" + ` (or
+passed via --team), so they can be reached when PagerDuty itself is degraded.
+
+With --vcard, one vCard per user is written, with their e-mail addresses and
+the phone numbers of their phone and SMS contact methods. The resulting file
+can be imported in any address book:
+
+  sre oncall user export --vcard -o oncall.vcf`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logrus.Debugf("Running oncall user export command")
+		ctx := context.Background()
+		cfg, err := GetConfig()
+		if err != nil {
+			return err
+		}
+		if !flagOncallUserExportVCard {
+			return fmt.Errorf("no export format specified, use --vcard")
+		}
+		teams := cfg.PagerDuty.Teams
+		if len(flagOncallUserExportTeams) > 0 {
+			teams = flagOncallUserExportTeams
+		}
+		client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+		teamIDs, err := resolveTeamIDs(ctx, client, teams)
+		if err != nil {
+			return err
+		}
+		users, err := listTeamUsers(ctx, client, teamIDs)
+		if err != nil {
+			return err
+		}
+
+		err = writeOutput(flagOncallUserExportOutput, func(w io.Writer) error {
+			for _, u := range users {
+				if err := writeVCard(w, u); err != nil {
+					return fmt.Errorf("failed to write vCard of %s: %w", u.Name, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if flagOncallUserExportOutput != "" {
+			fmt.Printf("Exported %d contacts to %s\n", len(users), flagOncallUserExportOutput)
+		}
+		return nil
+	},
+}
+
+// writeOutput calls write with the file at path, or with the standard output
+// if path is empty. The file is closed before returning, so that an error
+// flushing it is not lost.
+func writeOutput(path string, write func(io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+	fd, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", path, err)
+	}
+	if err := write(fd); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return fmt.Errorf("failed to write %q: %w", path, err)
+	}
+	return nil
+}
+
+// contactMethodPhone returns the phone number of a phone or SMS contact
+// method in international format.
+func contactMethodPhone(c pagerduty.ContactMethod) string {
+	return fmt.Sprintf("+%d%s", c.CountryCode, c.Address)
+}
+
+var vCardEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\n", `\n`)
+
+// writeVCard writes a vCard 3.0 for the user, with their e-mail addresses
+// and the numbers of their phone and SMS contact methods.
+func writeVCard(w io.Writer, u *pagerduty.User) error {
+	var lines []string
+	add := func(format string, a ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, a...))
+	}
+	add("BEGIN:VCARD")
+	add("VERSION:3.0")
+	add("FN:%s", vCardEscaper.Replace(u.Name))
+	given, family := u.Name, ""
+	if idx := strings.LastIndex(u.Name, " "); idx >= 0 {
+		given, family = u.Name[:idx], u.Name[idx+1:]
+	}
+	add("N:%s;%s;;;", vCardEscaper.Replace(family), vCardEscaper.Replace(given))
+	if u.JobTitle != "" {
+		add("TITLE:%s", vCardEscaper.Replace(u.JobTitle))
+	}
+
+	emails := []string{u.Email}
+	phoneTypes := make(map[string][]string)
+	var phones []string
+	for _, c := range u.ContactMethods {
+		var typ string
+		switch contactMethodType(c) {
+		case "email_contact_method":
+			if !strings.EqualFold(c.Address, u.Email) {
+				emails = append(emails, c.Address)
+			}
+			continue
+		case "phone_contact_method":
+			typ = "VOICE"
+		case "sms_contact_method":
+			typ = "TEXT"
+		default:
+			continue
+		}
+		phone := contactMethodPhone(c)
+		types, ok := phoneTypes[phone]
+		if !ok {
+			phones = append(phones, phone)
+		}
+		if !slices.Contains(types, typ) {
+			phoneTypes[phone] = append(types, typ)
+		}
+	}
+	for idx, e := range emails {
+		if e == "" {
+			continue
+		}
+		pref := ""
+		if idx == 0 {
+			pref = ",PREF"
+		}
+		add("EMAIL;TYPE=INTERNET%s:%s", pref, vCardEscaper.Replace(e))
+	}
+	for _, p := range phones {
+		types := phoneTypes[p]
+		sort.Strings(types)
+		add("TEL;TYPE=CELL,%s:%s", strings.Join(types, ","), p)
+	}
+	if u.HTMLURL != "" {
+		add("URL:%s", u.HTMLURL)
+	}
+	note := "PagerDuty user " + u.ID
+	if u.Timezone != "" {
+		note += ", time zone " + u.Timezone
+	}
+	add("NOTE:%s", vCardEscaper.Replace(note))
+	add("END:VCARD")
+	// vCard lines are terminated by CRLF
+	_, err := io.WriteString(w, strings.Join(lines, "\r\n")+"\r\n")
+	return err
+}
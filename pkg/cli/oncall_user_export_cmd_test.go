@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestWriteVCard(t *testing.T) {
+	u := pagerduty.User{
+		APIObject: pagerduty.APIObject{ID: "PUSER01", HTMLURL: "https://example.pagerduty.com/users/PUSER01"},
+		Name:      "Alice van Smith",
+		Email:     "alice@example.com",
+		JobTitle:  "SRE, payments",
+		Timezone:  "Europe/Rome",
+		ContactMethods: []pagerduty.ContactMethod{
+			{Type: "email_contact_method_reference", Address: "alice@example.com"},
+			{Type: "email_contact_method", Address: "alice@home.example"},
+			{Type: "phone_contact_method", CountryCode: 39, Address: "3331234567"},
+			{Type: "sms_contact_method", CountryCode: 39, Address: "3331234567"},
+			{Type: "phone_contact_method", CountryCode: 39, Address: "3331234567"},
+			{Type: "push_notification_contact_method", Address: "iPhone"},
+		},
+	}
+	var b strings.Builder
+	if err := writeVCard(&b, &u); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		"FN:Alice van Smith",
+		"N:Smith;Alice van;;;",
+		`TITLE:SRE\, payments`,
+		"EMAIL;TYPE=INTERNET,PREF:alice@example.com",
+		"EMAIL;TYPE=INTERNET:alice@home.example",
+		"TEL;TYPE=CELL,TEXT,VOICE:+393331234567",
+		"URL:https://example.pagerduty.com/users/PUSER01",
+		`NOTE:PagerDuty user PUSER01\, time zone Europe/Rome`,
+		"END:VCARD",
+	}, "\r\n") + "\r\n"
+	if b.String() != want {
+		t.Fatalf("got vCard:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultConcurrency is the default number of concurrent PagerDuty requests
+// for commands resolving many objects at once. PagerDuty rate limits the
+// REST API per user token, and requests hitting the limit are retried by
+// withRateLimitRetries.
+const defaultConcurrency = 4
+
+// maxRateLimitRetries is how many times a rate limited request is retried
+// before giving up.
+const maxRateLimitRetries = 5
+
+// maxRateLimitDelay caps how long we wait before retrying a rate limited
+// request.
+const maxRateLimitDelay = 30 * time.Second
+
+// rateLimitRetryingClient is a pagerduty.HTTPClient that retries requests
+// rejected with 429 Too Many Requests, waiting as long as the response says
+// or backing off exponentially.
+type rateLimitRetryingClient struct {
+	client pagerduty.HTTPClient
+	sleep  func(time.Duration)
+}
+
+// withRateLimitRetries makes the PagerDuty client retry rate limited
+// requests.
+func withRateLimitRetries() pagerduty.ClientOptions {
+	return func(c *pagerduty.Client) {
+		c.HTTPClient = &rateLimitRetryingClient{client: c.HTTPClient, sleep: time.Sleep}
+	}
+}
+
+func (c *rateLimitRetryingClient) Do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.client.Do(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt == maxRateLimitRetries {
+			return resp, err
+		}
+		// the body of the request must be replayed, give up if we can't
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return resp, nil
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return resp, nil
+			}
+			req.Body = body
+		}
+		delay := rateLimitDelay(resp.Header, attempt)
+		resp.Body.Close()
+		logrus.Debugf("Rate limited by PagerDuty on %s, retrying in %s", req.URL.Path, delay)
+		c.sleep(delay)
+	}
+}
+
+// rateLimitDelay returns how long to wait before retrying a rate limited
+// request, from the Retry-After or PagerDuty's ratelimit-reset headers (in
+// seconds), or backing off exponentially from one second.
+func rateLimitDelay(h http.Header, attempt int) time.Duration {
+	delay := time.Second << attempt
+	for _, name := range []string{"Retry-After", "Ratelimit-Reset"} {
+		if secs, err := strconv.Atoi(h.Get(name)); err == nil && secs > 0 {
+			delay = time.Duration(secs) * time.Second
+			break
+		}
+	}
+	if delay > maxRateLimitDelay {
+		delay = maxRateLimitDelay
+	}
+	return delay
+}
+
+// forEachConcurrently calls fn for each index in [0, count), with at most
+// concurrency calls running at the same time, and waits for all of them.
+// Callers preserve ordering by storing results at their index.
+func forEachConcurrently(concurrency, count int, fn func(idx int)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for idx := 0; idx < count; idx++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(idx int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(idx)
+		}(idx)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeHTTPClient struct {
+	statuses []int
+	bodies   []string
+}
+
+func (c *fakeHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		b, _ := io.ReadAll(req.Body)
+		c.bodies = append(c.bodies, string(b))
+	}
+	status := c.statuses[0]
+	if len(c.statuses) > 1 {
+		c.statuses = c.statuses[1:]
+	}
+	h := http.Header{}
+	if status == http.StatusTooManyRequests {
+		h.Set("Ratelimit-Reset", "2")
+	}
+	return &http.Response{StatusCode: status, Header: h, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func TestRateLimitRetryingClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		wantStatus int
+		wantSleeps int
+	}{
+		{name: "no rate limit", statuses: []int{200}, wantStatus: 200},
+		{name: "retried", statuses: []int{429, 429, 200}, wantStatus: 200, wantSleeps: 2},
+		{name: "gives up", statuses: []int{429}, wantStatus: 429, wantSleeps: maxRateLimitRetries},
+		{name: "other errors are not retried", statuses: []int{500, 200}, wantStatus: 500},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeHTTPClient{statuses: tc.statuses}
+			var sleeps []time.Duration
+			c := &rateLimitRetryingClient{client: fake, sleep: func(d time.Duration) { sleeps = append(sleeps, d) }}
+			req, err := http.NewRequest(http.MethodPost, "https://api.pagerduty.com/incidents", strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.StatusCode != tc.wantStatus {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tc.wantStatus)
+			}
+			if len(sleeps) != tc.wantSleeps {
+				t.Errorf("slept %d times, want %d", len(sleeps), tc.wantSleeps)
+			}
+			for _, d := range sleeps {
+				if d != 2*time.Second {
+					t.Errorf("slept %s, want 2s from the ratelimit-reset header", d)
+				}
+			}
+			for idx, b := range fake.bodies {
+				if b != "payload" {
+					t.Errorf("attempt %d sent body %q, want %q", idx, b, "payload")
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  http.Header
+		attempt int
+		want    time.Duration
+	}{
+		{name: "exponential backoff", header: http.Header{}, attempt: 2, want: 4 * time.Second},
+		{name: "retry-after", header: http.Header{"Retry-After": {"7"}}, attempt: 2, want: 7 * time.Second},
+		{name: "capped", header: http.Header{"Retry-After": {"3600"}}, want: maxRateLimitDelay},
+		{name: "invalid header", header: http.Header{"Retry-After": {"soon"}}, want: time.Second},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := rateLimitDelay(tc.header, tc.attempt); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestForEachConcurrently(t *testing.T) {
+	const count, concurrency = 50, 3
+	var (
+		mu              sync.Mutex
+		running, maxRun int
+	)
+	results := make([]int, count)
+	forEachConcurrently(concurrency, count, func(idx int) {
+		mu.Lock()
+		running++
+		if running > maxRun {
+			maxRun = running
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		results[idx] = idx * idx
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+	if maxRun > concurrency {
+		t.Errorf("%d calls ran concurrently, want at most %d", maxRun, concurrency)
+	}
+	for idx, r := range results {
+		if r != idx*idx {
+			t.Errorf("result %d: got %d, want %d", idx, r, idx*idx)
+		}
+	}
+}
+
+func TestBatchStrings(t *testing.T) {
+	got := batchStrings([]string{"a", "b", "c", "d", "e"}, 2)
+	if len(got) != 3 || len(got[0]) != 2 || len(got[2]) != 1 || got[2][0] != "e" {
+		t.Errorf("got %v, want [[a b] [c d] [e]]", got)
+	}
+	if got := batchStrings(nil, 2); len(got) != 0 {
+		t.Errorf("got %v for no items, want no batches", got)
+	}
+}
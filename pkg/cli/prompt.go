@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// confirm asks a yes/no question on stdout and reads the answer from stdin.
+// Anything other than "y" is a no.
+func confirm(question string) (bool, error) {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("%s [yN] ", question)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf("failed to read from stdin: %w", err)
+	}
+	input = strings.TrimSpace(strings.ToLower(input))
+	return input == "y", nil
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal.
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// errNotPicked is returned by pick when the user aborts the selection.
+var errNotPicked = fmt.Errorf("no selection made, aborting")
+
+// pick asks the user to choose one of the items, and returns its index. The
+// items are printed as a numbered list: typing a number selects that item,
+// typing anything else filters the list down to the items containing all the
+// typed words (case-insensitively), and selects the item if only one is left.
+// An empty answer aborts.
+//
+// When stdin is not a terminal nobody can answer, so an error listing the
+// candidates is returned instead. `what` describes the items in the messages,
+// e.g. "users matching \"alex\"".
+func pick(what string, items []string) (int, error) {
+	if len(items) == 1 {
+		return 0, nil
+	}
+	if !stdinIsTerminal() {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "found %d %s, try using a narrower search:", len(items), what)
+		for idx, item := range items {
+			fmt.Fprintf(&sb, "\n%d)  %s", idx+1, item)
+		}
+		return -1, fmt.Errorf("%s", sb.String())
+	}
+	reader := bufio.NewReader(os.Stdin)
+	visible := make([]int, len(items))
+	for idx := range items {
+		visible[idx] = idx
+	}
+	for {
+		fmt.Printf("Found %d %s:\n", len(visible), what)
+		for n, idx := range visible {
+			fmt.Printf("%d)  %s\n", n+1, items[idx])
+		}
+		fmt.Printf("Select one by number, or type to filter (empty to abort): ")
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return -1, fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			return -1, errNotPicked
+		}
+		if n, err := strconv.Atoi(input); err == nil {
+			if n < 1 || n > len(visible) {
+				fmt.Printf("Invalid choice %d\n", n)
+				continue
+			}
+			return visible[n-1], nil
+		}
+		filtered := filterItems(items, visible, input)
+		switch len(filtered) {
+		case 0:
+			fmt.Printf("Nothing matches %q\n", input)
+		case 1:
+			return filtered[0], nil
+		default:
+			visible = filtered
+		}
+	}
+}
+
+// filterItems returns the indices in candidates whose item contains every
+// word of filter, ignoring case.
+func filterItems(items []string, candidates []int, filter string) []int {
+	words := strings.Fields(strings.ToLower(filter))
+	var out []int
+	for _, idx := range candidates {
+		item := strings.ToLower(items[idx])
+		match := true
+		for _, w := range words {
+			if !strings.Contains(item, w) {
+				match = false
+				break
+			}
+		}
+		if match {
+			out = append(out, idx)
+		}
+	}
+	return out
+}
+
+// deselect lists the items and asks the user which ones to drop, by number
+// or range, e.g. "2 5-7". It returns the indices of the items to keep. An
+// empty answer keeps them all.
+func deselect(what string, items []string) ([]int, error) {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("Found %d %s:\n", len(items), what)
+		for idx, item := range items {
+			fmt.Printf("%d)  %s\n", idx+1, item)
+		}
+		fmt.Printf("Numbers or ranges to deselect, e.g. \"2 5-7\" (empty to keep all): ")
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		drop, err := parseSelection(input, len(items))
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			continue
+		}
+		var keep []int
+		for idx := range items {
+			if _, ok := drop[idx]; !ok {
+				keep = append(keep, idx)
+			}
+		}
+		return keep, nil
+	}
+}
+
+// parseSelection parses a list of 1-based numbers and ranges separated by
+// spaces or commas, e.g. "1, 3 5-7", into a set of 0-based indices lower
+// than count.
+func parseSelection(input string, count int) (map[int]struct{}, error) {
+	selected := make(map[int]struct{})
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' }) {
+		from, to, isRange := strings.Cut(field, "-")
+		start, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", from)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(to)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", to)
+			}
+		}
+		if start < 1 || end > count || start > end {
+			return nil, fmt.Errorf("invalid choice %q, must be between 1 and %d", field, count)
+		}
+		for n := start; n <= end; n++ {
+			selected[n-1] = struct{}{}
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,70 @@
+package cli
+
+import "testing"
+
+func TestFilterItems(t *testing.T) {
+	items := []string{
+		"Alex Smith <asmith@example.com> SRE",
+		"Alexandra Jones <ajones@example.com> Engineering Manager",
+		"Alex Jones <alex.jones@example.com> SRE",
+	}
+	all := []int{0, 1, 2}
+	tests := []struct {
+		filter string
+		want   []int
+	}{
+		{filter: "jones", want: []int{1, 2}},
+		{filter: "JONES sre", want: []int{2}},
+		{filter: "smith", want: []int{0}},
+		{filter: "bob", want: nil},
+	}
+	for _, tc := range tests {
+		got := filterItems(items, all, tc.filter)
+		if len(got) != len(tc.want) {
+			t.Fatalf("filter %q: got %v, want %v", tc.filter, got, tc.want)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Fatalf("filter %q: got %v, want %v", tc.filter, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []int
+		wantErr bool
+	}{
+		{input: "", want: nil},
+		{input: "2", want: []int{1}},
+		{input: "1, 3 5-7\n", want: []int{0, 2, 4, 5, 6}},
+		{input: "0", wantErr: true},
+		{input: "8", wantErr: true},
+		{input: "5-3", wantErr: true},
+		{input: "two", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := parseSelection(tc.input, 7)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("input %q: expected an error, got %v", tc.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("input %q: unexpected error: %v", tc.input, err)
+			continue
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("input %q: got %v, want %v", tc.input, got, tc.want)
+			continue
+		}
+		for _, idx := range tc.want {
+			if _, ok := got[idx]; !ok {
+				t.Errorf("input %q: got %v, want %v", tc.input, got, tc.want)
+			}
+		}
+	}
+}
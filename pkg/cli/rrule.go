@@ -0,0 +1,244 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRecurrences caps the number of occurrences a recurrence rule can expand
+// to, so that a rule without a reasonable end doesn't flood PagerDuty with
+// overrides.
+const maxRecurrences = 500
+
+// maxRecurrenceDays caps how far in the future a recurrence rule is expanded.
+const maxRecurrenceDays = 5 * 366
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// recurrenceRule is the subset of RFC 5545 recurrence rules that makes sense
+// for overrides: FREQ (DAILY, WEEKLY or MONTHLY), INTERVAL, BYDAY (without
+// ordinals), BYMONTHDAY, COUNT and UNTIL.
+type recurrenceRule struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Count      int
+	// Until is the last day (inclusive) an occurrence can start on, or the
+	// zero time if unbounded.
+	Until time.Time
+}
+
+// parseRecurrenceRule parses a rule like "FREQ=WEEKLY;BYDAY=TU,TH". An optional
+// "RRULE:" prefix is accepted. UNTIL dates are interpreted in loc.
+func parseRecurrenceRule(s string, loc *time.Location) (*recurrenceRule, error) {
+	r := recurrenceRule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule part %q, expected KEY=VALUE", part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY":
+				r.Freq = value
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q, must be one of DAILY, WEEKLY, MONTHLY", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", value)
+			}
+			r.Count = n
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := rruleWeekdays[d]
+				if !ok {
+					return nil, fmt.Errorf("unsupported BYDAY value %q", d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n < 1 || n > 31 {
+					return nil, fmt.Errorf("unsupported BYMONTHDAY value %q", d)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "UNTIL":
+			var (
+				t   time.Time
+				err error
+			)
+			if strings.HasSuffix(value, "Z") {
+				t, err = time.Parse("20060102T150405Z", value)
+				t = t.In(loc)
+			} else {
+				t, err = time.ParseInLocation("20060102", value, loc)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %q", value)
+			}
+			r.Until = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+	if r.Freq == "" {
+		return nil, fmt.Errorf("missing FREQ in rule %q", s)
+	}
+	return &r, nil
+}
+
+// clockTime is a time of the day, in minutes since midnight.
+type clockTime int
+
+func parseClockTime(s string) (clockTime, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return clockTime(t.Hour()*60 + t.Minute()), nil
+}
+
+// on returns the instant at the given clock time on the day of d, in loc.
+// Going through time.Date keeps the wall clock time across DST changes.
+func (c clockTime) on(d time.Time, loc *time.Location) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), int(c)/60, int(c)%60, 0, 0, loc)
+}
+
+// timeRange is a [Start, End) interval.
+type timeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Expand returns the occurrences of the rule starting at or after from, the
+// DTSTART of the recurrence, each going from start to end (wall clock, in
+// loc). If end is not after start the occurrence ends on the following day.
+// COUNT counts the occurrences from DTSTART, so with from set to now the
+// occurrences of today that already started are neither returned nor counted.
+// until, if not zero, is an additional inclusive last day on top of the
+// rule's own UNTIL. Expansion fails if neither COUNT nor an until date bound
+// the rule.
+func (r *recurrenceRule) Expand(from time.Time, start, end clockTime, until time.Time, loc *time.Location) ([]timeRange, error) {
+	last := r.Until
+	if !until.IsZero() {
+		u := time.Date(until.In(loc).Year(), until.In(loc).Month(), until.In(loc).Day(), 0, 0, 0, 0, loc)
+		if last.IsZero() || u.Before(last) {
+			last = u
+		}
+	}
+	if last.IsZero() && r.Count == 0 {
+		return nil, fmt.Errorf("the recurrence has no end, set COUNT or UNTIL in the rule, or pass an until date")
+	}
+	from = from.In(loc)
+	first := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+
+	var out []timeRange
+	// count calendar days rather than dividing durations, which are off by
+	// an hour across DST changes
+	for days, day := 0, first; last.IsZero() || !day.After(last); days, day = days+1, day.AddDate(0, 0, 1) {
+		if days > maxRecurrenceDays {
+			return nil, fmt.Errorf("the recurrence spans more than %d days", maxRecurrenceDays)
+		}
+		if r.matches(first, day, days) {
+			s := start.on(day, loc)
+			e := end.on(day, loc)
+			if !e.After(s) {
+				e = end.on(day.AddDate(0, 0, 1), loc)
+			}
+			if s.Before(from) {
+				// before DTSTART, not part of the recurrence
+				continue
+			}
+			out = append(out, timeRange{Start: s, End: e})
+			if r.Count > 0 && len(out) >= r.Count {
+				break
+			}
+		}
+		if len(out) > maxRecurrences {
+			return nil, fmt.Errorf("the recurrence expands to more than %d occurrences", maxRecurrences)
+		}
+	}
+	return out, nil
+}
+
+// matches reports whether an occurrence falls on day, which is the given
+// number of calendar days after first, the first possible occurrence. Both
+// are midnights in the same location.
+func (r *recurrenceRule) matches(first, day time.Time, days int) bool {
+	byDay := func(def time.Weekday) bool {
+		wds := r.ByDay
+		if len(wds) == 0 {
+			wds = []time.Weekday{def}
+		}
+		for _, wd := range wds {
+			if day.Weekday() == wd {
+				return true
+			}
+		}
+		return false
+	}
+	byMonthDay := func(def int) bool {
+		mds := r.ByMonthDay
+		if len(mds) == 0 {
+			mds = []int{def}
+		}
+		for _, md := range mds {
+			if day.Day() == md {
+				return true
+			}
+		}
+		return false
+	}
+	switch r.Freq {
+	case "DAILY":
+		if days%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) > 0 && !byDay(day.Weekday()) {
+			return false
+		}
+		return len(r.ByMonthDay) == 0 || byMonthDay(day.Day())
+	case "WEEKLY":
+		// weeks start on Monday, as in RFC 5545
+		weeks := (days + (int(first.Weekday())+6)%7) / 7
+		return weeks%r.Interval == 0 && byDay(first.Weekday())
+	case "MONTHLY":
+		months := (day.Year()-first.Year())*12 + int(day.Month()) - int(first.Month())
+		if months%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) > 0 && len(r.ByMonthDay) == 0 {
+			return byDay(day.Weekday())
+		}
+		return byMonthDay(first.Day()) && (len(r.ByDay) == 0 || byDay(day.Weekday()))
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceRuleExpand(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	day := func(s string) time.Time {
+		layout := time.DateOnly
+		if len(s) > len(layout) {
+			layout = "2006-01-02T15:04"
+		}
+		d, err := time.ParseInLocation(layout, s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	nine, _ := parseClockTime("09:00")
+	five, _ := parseClockTime("17:00")
+	eight, _ := parseClockTime("08:00")
+
+	tests := []struct {
+		name       string
+		rule       string
+		from       string
+		until      string
+		start, end clockTime
+		want       []string // RFC3339 start/end pairs
+		wantErr    bool
+	}{
+		{
+			// DST ends on Sunday 25 Oct 2026 in Europe/Rome: the offset
+			// changes but the wall clock time doesn't
+			name: "weekly on tuesday across DST", rule: "FREQ=WEEKLY;BYDAY=TU", from: "2026-10-19", until: "2026-11-03",
+			start: nine, end: five,
+			want: []string{
+				"2026-10-20T09:00:00+02:00", "2026-10-20T17:00:00+02:00",
+				"2026-10-27T09:00:00+01:00", "2026-10-27T17:00:00+01:00",
+				"2026-11-03T09:00:00+01:00", "2026-11-03T17:00:00+01:00",
+			},
+		},
+		{
+			name: "overnight occurrences end the next day", rule: "FREQ=DAILY;COUNT=2", from: "2026-10-24",
+			start: five, end: eight,
+			want: []string{
+				"2026-10-24T17:00:00+02:00", "2026-10-25T08:00:00+01:00",
+				"2026-10-25T17:00:00+01:00", "2026-10-26T08:00:00+01:00",
+			},
+		},
+		{
+			name: "every other week", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", from: "2026-10-21", until: "2026-11-06",
+			start: nine, end: five,
+			want: []string{
+				"2026-10-23T09:00:00+02:00", "2026-10-23T17:00:00+02:00",
+				"2026-11-02T09:00:00+01:00", "2026-11-02T17:00:00+01:00",
+				"2026-11-06T09:00:00+01:00", "2026-11-06T17:00:00+01:00",
+			},
+		},
+		{
+			name: "monthly by month day with rule UNTIL", rule: "RRULE:FREQ=MONTHLY;BYMONTHDAY=1;UNTIL=20261201", from: "2026-10-19",
+			start: nine, end: five,
+			want: []string{
+				"2026-11-01T09:00:00+01:00", "2026-11-01T17:00:00+01:00",
+				"2026-12-01T09:00:00+01:00", "2026-12-01T17:00:00+01:00",
+			},
+		},
+		{
+			// the occurrence of the 20th started before DTSTART, so it is
+			// not counted
+			name: "count from a DTSTART after the first occurrence started", rule: "FREQ=DAILY;COUNT=2", from: "2026-10-20T10:00",
+			start: nine, end: five,
+			want: []string{
+				"2026-10-21T09:00:00+02:00", "2026-10-21T17:00:00+02:00",
+				"2026-10-22T09:00:00+02:00", "2026-10-22T17:00:00+02:00",
+			},
+		},
+		{name: "unbounded", rule: "FREQ=DAILY", from: "2026-10-19", start: nine, end: five, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := parseRecurrenceRule(tc.rule, loc)
+			if err != nil {
+				t.Fatalf("parseRecurrenceRule: %v", err)
+			}
+			var until time.Time
+			if tc.until != "" {
+				until = day(tc.until)
+			}
+			got, err := r.Expand(day(tc.from), tc.start, tc.end, until, loc)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expand: %v", err)
+			}
+			var gotStrs []string
+			for _, o := range got {
+				gotStrs = append(gotStrs, o.Start.Format(time.RFC3339), o.End.Format(time.RFC3339))
+			}
+			if len(gotStrs) != len(tc.want) {
+				t.Fatalf("got %v, want %v", gotStrs, tc.want)
+			}
+			for i := range gotStrs {
+				if gotStrs[i] != tc.want[i] {
+					t.Fatalf("got %v, want %v", gotStrs, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRecurrenceRuleErrors(t *testing.T) {
+	for _, rule := range []string{"", "BYDAY=TU", "FREQ=YEARLY", "FREQ=WEEKLY;BYDAY=1TU", "FREQ=DAILY;INTERVAL=0", "FREQ"} {
+		if _, err := parseRecurrenceRule(rule, time.UTC); err == nil {
+			t.Errorf("rule %q: expected error", rule)
+		}
+	}
+}
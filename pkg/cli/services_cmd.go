@@ -0,0 +1,258 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/sre/pkg/ansi"
+	"github.com/insomniacslk/sre/pkg/config"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewServicesCmd(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "services",
+		Aliases: []string{"service", "svc"},
+		Short:   "Show the services of the configured teams (PagerDuty)",
+		Args:    cobra.MinimumNArgs(1),
+	}
+	cmd.AddCommand(
+		newServicesListCmd(cfg),
+		newServicesShowCmd(cfg),
+		newServicesImpactCmd(cfg),
+	)
+	return cmd
+}
+
+// openIncidentStatuses are the statuses of incidents that are not resolved.
+// Incidents are listed by status with the "all" date range, since PagerDuty
+// only returns the last month of incidents otherwise.
+var openIncidentStatuses = []string{"triggered", "acknowledged"}
+
+func newServicesListCmd(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the services of the configured teams with their status",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logrus.Debugf("Running services list command")
+			ctx := context.Background()
+			loc, err := time.LoadLocation(cfg.Timezone)
+			if err != nil {
+				return fmt.Errorf("cannot load timezone %q: %w", cfg.Timezone, err)
+			}
+			client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+			teamIDs, err := resolveTeamIDs(ctx, client, cfg.PagerDuty.Teams)
+			if err != nil {
+				return err
+			}
+			services, err := client.ListServicesPaginated(ctx, pagerduty.ListServiceOptions{TeamIDs: teamIDs, Includes: []string{"escalation_policies"}})
+			if err != nil {
+				return fmt.Errorf("failed to list services: %w", err)
+			}
+			incidents, err := listIncidents(ctx, client, pagerduty.ListIncidentsOptions{TeamIDs: teamIDs, Statuses: openIncidentStatuses, DateRange: "all"})
+			if err != nil {
+				return fmt.Errorf("failed to list open incidents: %w", err)
+			}
+			open := make(map[string]int)
+			for _, i := range incidents {
+				open[i.Service.ID]++
+			}
+			maintenance, err := ongoingMaintenance(ctx, client, teamIDs)
+			if err != nil {
+				logrus.Warningf("%v", err)
+			}
+			for _, s := range services {
+				fmt.Printf("%s [ID: %s] %s\n", ansi.Bold(ansi.ToURL(s.Name, s.HTMLURL)), s.ID, formatServiceStatus(s.Status))
+				fmt.Printf("    Escalation policy: %s\n", ansi.ToURL(s.EscalationPolicy.Name, s.EscalationPolicy.HTMLURL))
+				fmt.Printf("    Open incidents   : %d\n", open[s.ID])
+				fmt.Printf("    Last incident    : %s\n", formatLastIncident(s.LastIncidentTimestamp, loc))
+				if mw, ok := maintenance[s.ID]; ok {
+					fmt.Printf("    Maintenance      : %s\n", maintenanceWarning(mw, loc))
+				}
+			}
+			fmt.Printf("Found %d services for teams matching %q\n", len(services), cfg.PagerDuty.Teams)
+			return nil
+		},
+	}
+}
+
+func newServicesShowCmd(cfg *config.Config) *cobra.Command {
+	var flagShowKeys bool
+	cmd := &cobra.Command{
+		Use:   "show <service>",
+		Short: "Show a service's status, urgency rules, support hours, integrations and open incidents",
+		Long: `Show a service's status, escalation policy, urgency rules, support hours,
+integrations, open incidents and last incident time.
+
+<service> is either a service ID or a query matching the names of the services
+of the configured teams. Integration keys are redacted unless --show-keys is
+passed.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logrus.Debugf("Running services show command")
+			ctx := context.Background()
+			loc, err := time.LoadLocation(cfg.Timezone)
+			if err != nil {
+				return fmt.Errorf("cannot load timezone %q: %w", cfg.Timezone, err)
+			}
+			client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+			teamIDs, err := resolveTeamIDs(ctx, client, cfg.PagerDuty.Teams)
+			if err != nil {
+				return err
+			}
+			s, err := lookupService(ctx, client, teamIDs, strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			incidents, err := listIncidents(ctx, client, pagerduty.ListIncidentsOptions{ServiceIDs: []string{s.ID}, Statuses: openIncidentStatuses, DateRange: "all"})
+			if err != nil {
+				return fmt.Errorf("failed to list open incidents of %q: %w", s.Name, err)
+			}
+			maintenance, err := ongoingMaintenance(ctx, client, teamIDs)
+			if err != nil {
+				logrus.Warningf("%v", err)
+			}
+
+			fmt.Printf(ansi.Bold("Name         :")+" %s\n", ansi.ToURL(s.Name, s.HTMLURL))
+			fmt.Printf(ansi.Bold("ID           :")+" %s\n", s.ID)
+			fmt.Printf(ansi.Bold("Description  :")+" %s\n", s.Description)
+			fmt.Printf(ansi.Bold("Status       :")+" %s\n", formatServiceStatus(s.Status))
+			if mw, ok := maintenance[s.ID]; ok {
+				fmt.Printf(ansi.Bold("Maintenance  :")+" %s\n", maintenanceWarning(mw, loc))
+			}
+			fmt.Printf(ansi.Bold("Escalation   :")+" %s\n", ansi.ToURL(s.EscalationPolicy.Name, s.EscalationPolicy.HTMLURL))
+			var teams []string
+			for _, t := range s.Teams {
+				teams = append(teams, ansi.ToURL(t.Summary, t.HTMLURL))
+			}
+			fmt.Printf(ansi.Bold("Teams        :")+" %s\n", strings.Join(teams, ", "))
+			fmt.Printf(ansi.Bold("Urgency      :")+" %s\n", describeUrgencyRule(s.IncidentUrgencyRule))
+			fmt.Printf(ansi.Bold("Support hours:")+" %s\n", describeSupportHours(s.SupportHours))
+			fmt.Printf(ansi.Bold("Last incident:")+" %s\n", formatLastIncident(s.LastIncidentTimestamp, loc))
+			fmt.Print(ansi.Bold("Integrations :\n"))
+			for _, i := range s.Integrations {
+				line := fmt.Sprintf("  %s (%s)", i.Name, strings.TrimSuffix(i.Type, "_reference"))
+				if i.IntegrationKey != "" {
+					key := i.IntegrationKey
+					if !flagShowKeys {
+						key = redactKey(key)
+					}
+					line += " key: " + key
+				}
+				if i.IntegrationEmail != "" {
+					line += " e-mail: " + i.IntegrationEmail
+				}
+				fmt.Println(line)
+			}
+			fmt.Printf(ansi.Bold("Open incidents:")+" %d\n", len(incidents))
+			for _, i := range incidents {
+				fmt.Printf("  #%d %s [%s, %s] %s\n", i.IncidentNumber, ansi.ToURL(i.Title, i.HTMLURL), i.Status, i.Urgency, formatLastIncident(i.CreatedAt, loc))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&flagShowKeys, "show-keys", false, "Show the integration keys instead of redacting them")
+	return cmd
+}
+
+// formatServiceStatus colors a service status by severity.
+func formatServiceStatus(status string) string {
+	switch status {
+	case "critical":
+		return color.RedString(status)
+	case "warning", "maintenance":
+		return color.YellowString(status)
+	case "active":
+		return color.GreenString(status)
+	default:
+		return status
+	}
+}
+
+// formatLastIncident formats the last incident timestamp of a service in loc,
+// with how long ago it was.
+func formatLastIncident(ts string, loc *time.Location) string {
+	if ts == "" {
+		return "never"
+	}
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return ts
+	}
+	return fmt.Sprintf("%s (%s ago)", t.In(loc).Format(shiftTimeFormat), time.Since(t).Round(time.Minute))
+}
+
+// redactKey hides all but the first and last 4 characters of a key.
+func redactKey(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + strings.Repeat("*", len(key)-8) + key[len(key)-4:]
+}
+
+// describeUrgencyRule describes the urgency of the incidents of a service.
+func describeUrgencyRule(r *pagerduty.IncidentUrgencyRule) string {
+	if r == nil {
+		return "unknown"
+	}
+	switch r.Type {
+	case "constant":
+		return r.Urgency
+	case "use_support_hours":
+		describe := func(t *pagerduty.IncidentUrgencyType) string {
+			if t == nil {
+				return "unknown"
+			}
+			if t.Type == "constant" {
+				return t.Urgency
+			}
+			return strings.ReplaceAll(t.Type, "_", " ")
+		}
+		return fmt.Sprintf("%s during support hours, %s outside", describe(r.DuringSupportHours), describe(r.OutsideSupportHours))
+	default:
+		return strings.ReplaceAll(r.Type, "_", " ")
+	}
+}
+
+// describeSupportHours describes the support hours of a service, e.g.
+// "Mon-Fri 09:00-17:00 Europe/Rome".
+func describeSupportHours(sh *pagerduty.SupportHours) string {
+	if sh == nil {
+		return "always"
+	}
+	days := make([]string, 0, len(sh.DaysOfWeek))
+	for _, d := range sh.DaysOfWeek {
+		// PagerDuty uses ISO 8601 days, 1 is Monday and 7 is Sunday
+		days = append(days, time.Weekday(d % 7).String()[:3])
+	}
+	daysStr := strings.Join(days, ",")
+	if len(sh.DaysOfWeek) > 2 && isConsecutive(sh.DaysOfWeek) {
+		daysStr = days[0] + "-" + days[len(days)-1]
+	}
+	return fmt.Sprintf("%s %s-%s %s", daysStr, trimSeconds(sh.StartTime), trimSeconds(sh.EndTime), sh.Timezone)
+}
+
+// trimSeconds turns a HH:MM:SS time of the day into HH:MM.
+func trimSeconds(s string) string {
+	if len(s) == len("15:04:05") {
+		return s[:len("15:04")]
+	}
+	return s
+}
+
+func isConsecutive(days []uint) bool {
+	for idx := 1; idx < len(days); idx++ {
+		if days[idx] != days[idx-1]+1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestRedactKey(t *testing.T) {
+	for key, want := range map[string]string{
+		"":                                 "",
+		"short":                            "*****",
+		"0123456789abcdef0123456789abcdef": "0123************************cdef",
+	} {
+		if got := redactKey(key); got != want {
+			t.Errorf("redactKey(%q): got %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestDescribeServiceRules(t *testing.T) {
+	urgencyTests := []struct {
+		rule *pagerduty.IncidentUrgencyRule
+		want string
+	}{
+		{rule: nil, want: "unknown"},
+		{rule: &pagerduty.IncidentUrgencyRule{Type: "constant", Urgency: "high"}, want: "high"},
+		{
+			rule: &pagerduty.IncidentUrgencyRule{
+				Type:                "use_support_hours",
+				DuringSupportHours:  &pagerduty.IncidentUrgencyType{Type: "constant", Urgency: "high"},
+				OutsideSupportHours: &pagerduty.IncidentUrgencyType{Type: "constant", Urgency: "low"},
+			},
+			want: "high during support hours, low outside",
+		},
+	}
+	for _, tc := range urgencyTests {
+		if got := describeUrgencyRule(tc.rule); got != tc.want {
+			t.Errorf("describeUrgencyRule(%+v): got %q, want %q", tc.rule, got, tc.want)
+		}
+	}
+
+	supportHoursTests := []struct {
+		sh   *pagerduty.SupportHours
+		want string
+	}{
+		{sh: nil, want: "always"},
+		{
+			sh:   &pagerduty.SupportHours{Timezone: "Europe/Rome", StartTime: "09:00:00", EndTime: "17:00:00", DaysOfWeek: []uint{1, 2, 3, 4, 5}},
+			want: "Mon-Fri 09:00-17:00 Europe/Rome",
+		},
+		{
+			sh:   &pagerduty.SupportHours{Timezone: "UTC", StartTime: "10:00:00", EndTime: "14:00:00", DaysOfWeek: []uint{6, 7}},
+			want: "Sat,Sun 10:00-14:00 UTC",
+		},
+	}
+	for _, tc := range supportHoursTests {
+		if got := describeSupportHours(tc.sh); got != tc.want {
+			t.Errorf("describeSupportHours(%+v): got %q, want %q", tc.sh, got, tc.want)
+		}
+	}
+}
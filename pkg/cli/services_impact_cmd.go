@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/insomniacslk/sre/pkg/config"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newServicesImpactCmd(cfg *config.Config) *cobra.Command {
+	var flagImpactedOnly bool
+	cmd := &cobra.Command{
+		Use:   "impact [filter]",
+		Short: "Show which business services are impacted by open incidents",
+		Long: `Show the business services with the technical services they depend on, as a
+tree, marking the ones that are impacted by open (triggered or acknowledged)
+incidents. A business service is impacted when any of the services it depends
+on, directly or transitively, has open incidents.
+
+With an optional [filter], only the business services whose name contains it
+(case-insensitive) are shown.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logrus.Debugf("Running services impact command")
+			ctx := context.Background()
+			client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+			businessServices, err := client.ListBusinessServicesPaginated(ctx, pagerduty.ListBusinessServiceOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to list business services: %w", err)
+			}
+			var roots []*pagerduty.BusinessService
+			for _, bs := range businessServices {
+				if len(args) == 0 || strings.Contains(strings.ToLower(bs.Name), strings.ToLower(args[0])) {
+					roots = append(roots, bs)
+				}
+			}
+			if len(roots) == 0 {
+				fmt.Printf("No business services found\n")
+				return nil
+			}
+			g, err := fetchDependencyGraph(ctx, client, businessServices, roots)
+			if err != nil {
+				return err
+			}
+			// dependencies can cross team boundaries, so look at the open
+			// incidents of all the teams
+			incidents, err := listIncidents(ctx, client, pagerduty.ListIncidentsOptions{Statuses: openIncidentStatuses, DateRange: "all"})
+			if err != nil {
+				return fmt.Errorf("failed to list open incidents: %w", err)
+			}
+			byService := make(map[string][]pagerduty.Incident)
+			for _, i := range incidents {
+				byService[i.Service.ID] = append(byService[i.Service.ID], i)
+			}
+			var (
+				trees    []*impactNode
+				impacted int
+			)
+			for _, bs := range roots {
+				n := buildImpactTree(g, bs.ID, byService, nil)
+				if n.impacted() {
+					impacted++
+				} else if flagImpactedOnly {
+					continue
+				}
+				trees = append(trees, n)
+			}
+			renderImpactTree(os.Stdout, trees)
+			fmt.Printf("%d of %d business services impacted by %d open incidents\n", impacted, len(roots), len(incidents))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&flagImpactedOnly, "impacted", "i", false, "Only show the impacted business services")
+	return cmd
+}
+
+// serviceNode is a business or technical service in a dependency graph.
+type serviceNode struct {
+	ID       string
+	Name     string
+	HTMLURL  string
+	Business bool
+}
+
+// dependencyGraph holds services and, for each of them, the IDs of the
+// services supporting it.
+type dependencyGraph struct {
+	Services   map[string]serviceNode
+	Supporting map[string][]string
+}
+
+// fetchDependencyGraph crawls the dependencies of the roots, down to the
+// technical services with no further dependencies.
+func fetchDependencyGraph(ctx context.Context, client *pagerduty.Client, businessServices, roots []*pagerduty.BusinessService) (*dependencyGraph, error) {
+	g := dependencyGraph{
+		Services:   make(map[string]serviceNode),
+		Supporting: make(map[string][]string),
+	}
+	for _, bs := range businessServices {
+		g.Services[bs.ID] = serviceNode{ID: bs.ID, Name: bs.Name, HTMLURL: bs.HTMLUrl, Business: true}
+	}
+	queue := make([]string, 0, len(roots))
+	for _, bs := range roots {
+		queue = append(queue, bs.ID)
+	}
+	visited := make(map[string]struct{})
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[id]; ok {
+			continue
+		}
+		visited[id] = struct{}{}
+		var (
+			deps *pagerduty.ListServiceDependencies
+			err  error
+		)
+		if g.Services[id].Business {
+			deps, err = client.ListBusinessServiceDependenciesWithContext(ctx, id)
+		} else {
+			deps, err = client.ListTechnicalServiceDependenciesWithContext(ctx, id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list dependencies of %q: %w", g.Services[id].Name, err)
+		}
+		for _, r := range deps.Relationships {
+			// the relationships include the services depending on this one
+			if r.DependentService == nil || r.SupportingService == nil || r.DependentService.ID != id {
+				continue
+			}
+			supportingID := r.SupportingService.ID
+			g.Supporting[id] = append(g.Supporting[id], supportingID)
+			if _, ok := g.Services[supportingID]; !ok {
+				if strings.HasPrefix(r.SupportingService.Type, "business_service") {
+					bs, err := client.GetBusinessServiceWithContext(ctx, supportingID)
+					if err != nil {
+						return nil, fmt.Errorf("failed to get business service with ID %q: %w", supportingID, err)
+					}
+					g.Services[supportingID] = serviceNode{ID: bs.ID, Name: bs.Name, HTMLURL: bs.HTMLUrl, Business: true}
+				} else {
+					s, err := client.GetServiceWithContext(ctx, supportingID, &pagerduty.GetServiceOptions{})
+					if err != nil {
+						return nil, fmt.Errorf("failed to get service with ID %q: %w", supportingID, err)
+					}
+					g.Services[supportingID] = serviceNode{ID: s.ID, Name: s.Name, HTMLURL: s.HTMLURL}
+				}
+			}
+			queue = append(queue, supportingID)
+		}
+	}
+	return &g, nil
+}
+
+// impactNode is a service in an impact tree, with its open incidents and the
+// services it depends on.
+type impactNode struct {
+	Service   serviceNode
+	Incidents []pagerduty.Incident
+	Children  []*impactNode
+	// Cycle is set when the service already appears among its ancestors, in
+	// which case its dependencies are not expanded again.
+	Cycle bool
+}
+
+// impacted reports whether the service or any of its dependencies has open
+// incidents.
+func (n *impactNode) impacted() bool {
+	if len(n.Incidents) > 0 {
+		return true
+	}
+	for _, c := range n.Children {
+		if c.impacted() {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *impactNode) description() string {
+	desc := n.Service.Name
+	if desc == "" {
+		desc = n.Service.ID
+	}
+	if n.Service.Business {
+		desc += " (business service)"
+	}
+	if n.Cycle {
+		desc += " (dependency cycle)"
+	}
+	switch {
+	case len(n.Incidents) == 1:
+		desc += " — " + color.RedString("1 open incident")
+	case len(n.Incidents) > 1:
+		desc += " — " + color.RedString("%d open incidents", len(n.Incidents))
+	case n.impacted():
+		desc += " — " + color.YellowString("impacted")
+	}
+	return desc
+}
+
+// buildImpactTree builds the dependency tree rooted at the service with the
+// given ID. ancestors tracks the services on the current path to stop at
+// dependency cycles.
+func buildImpactTree(g *dependencyGraph, id string, incidents map[string][]pagerduty.Incident, ancestors map[string]struct{}) *impactNode {
+	n := impactNode{Service: g.Services[id], Incidents: incidents[id]}
+	if n.Service.ID == "" {
+		n.Service.ID = id
+	}
+	if _, ok := ancestors[id]; ok {
+		n.Cycle = true
+		return &n
+	}
+	path := make(map[string]struct{}, len(ancestors)+1)
+	for a := range ancestors {
+		path[a] = struct{}{}
+	}
+	path[id] = struct{}{}
+	for _, child := range g.Supporting[id] {
+		n.Children = append(n.Children, buildImpactTree(g, child, incidents, path))
+	}
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		return n.Children[i].Service.Name < n.Children[j].Service.Name
+	})
+	return &n
+}
+
+// renderImpactTree renders the impact trees, listing the open incidents under
+// the services they belong to.
+func renderImpactTree(w io.Writer, trees []*impactNode) {
+	var render func(n *impactNode, indent string)
+	render = func(n *impactNode, indent string) {
+		count := len(n.Incidents) + len(n.Children)
+		idx := 0
+		for _, i := range n.Incidents {
+			fmt.Fprintf(w, "%s%s #%d %s [%s, %s]\n", indent, treeBranch(idx, count), i.IncidentNumber, i.Title, i.Status, i.Urgency)
+			idx++
+		}
+		for _, c := range n.Children {
+			fmt.Fprintf(w, "%s%s %s\n", indent, treeBranch(idx, count), c.description())
+			render(c, indent+treeIndent(idx == count-1))
+			idx++
+		}
+	}
+	for _, t := range trees {
+		fmt.Fprintf(w, "%s\n", t.description())
+		render(t, "")
+	}
+}
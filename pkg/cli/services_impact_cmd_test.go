@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestImpactTree(t *testing.T) {
+	g := &dependencyGraph{
+		Services: map[string]serviceNode{
+			"BS1": {ID: "BS1", Name: "Checkout", Business: true},
+			"BS2": {ID: "BS2", Name: "Search", Business: true},
+			"S1":  {ID: "S1", Name: "payments-api"},
+			"S2":  {ID: "S2", Name: "postgres"},
+			"S3":  {ID: "S3", Name: "frontend"},
+			"S4":  {ID: "S4", Name: "elasticsearch"},
+		},
+		Supporting: map[string][]string{
+			"BS1": {"S3", "S1"},
+			"S1":  {"S2"},
+			"S2":  {"S1"}, // cycle
+			"BS2": {"S3", "S4"},
+		},
+	}
+	incidents := map[string][]pagerduty.Incident{
+		"S2": {{IncidentNumber: 42, Title: "disk full", Status: "triggered", Urgency: "high"}},
+	}
+	checkout := buildImpactTree(g, "BS1", incidents, nil)
+	search := buildImpactTree(g, "BS2", incidents, nil)
+	if !checkout.impacted() {
+		t.Errorf("Checkout should be impacted")
+	}
+	if search.impacted() {
+		t.Errorf("Search should not be impacted")
+	}
+
+	var sb strings.Builder
+	renderImpactTree(&sb, []*impactNode{checkout, search})
+	want := `Checkout (business service) — impacted
+├── frontend
+└── payments-api — impacted
+    └── postgres — 1 open incident
+        ├── #42 disk full [triggered, high]
+        └── payments-api (dependency cycle)
+Search (business service)
+├── elasticsearch
+└── frontend
+`
+	if got := sb.String(); got != want {
+		t.Errorf("renderImpactTree() =\n%s\nwant\n%s", got, want)
+	}
+}
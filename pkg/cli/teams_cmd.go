@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/insomniacslk/sre/pkg/ansi"
+	"github.com/insomniacslk/sre/pkg/config"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const teamsLong = `Teams are resolved like in ` + "`pagerduty.teams`" + `: a name prefixed by "+" is a
+team ID, anything else is a query matching team names.`
+
+func NewTeamsCmd(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "teams",
+		Aliases: []string{"team"},
+		Short:   "Show and manage teams (PagerDuty)",
+		Long:    "Show and manage teams, their members and what they own.\n\n" + teamsLong,
+		Args:    cobra.MinimumNArgs(1),
+	}
+	cmd.AddCommand(
+		newTeamsListCmd(cfg),
+		newTeamsShowCmd(cfg),
+		newTeamsAddMemberCmd(cfg),
+		newTeamsRemoveMemberCmd(cfg),
+	)
+	return cmd
+}
+
+func newTeamsListCmd(cfg *config.Config) *cobra.Command {
+	var flagAll bool
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the configured teams, or all of them with --all",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logrus.Debugf("Running teams list command")
+			ctx := context.Background()
+			client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+			var teams []pagerduty.Team
+			if flagAll {
+				opts := pagerduty.ListTeamOptions{Limit: 100} // 100 is the maximum allowed by PagerDuty's API
+				for {
+					resp, err := client.ListTeamsWithContext(ctx, opts)
+					if err != nil {
+						return fmt.Errorf("failed to list teams: %w", err)
+					}
+					teams = append(teams, resp.Teams...)
+					if !resp.More || len(resp.Teams) == 0 {
+						break
+					}
+					opts.Offset += uint(len(resp.Teams))
+				}
+			} else {
+				teamIDs, err := resolveTeamIDs(ctx, client, cfg.PagerDuty.Teams)
+				if err != nil {
+					return err
+				}
+				for _, teamID := range teamIDs {
+					team, err := client.GetTeamWithContext(ctx, teamID)
+					if err != nil {
+						return fmt.Errorf("failed to get team with ID %q: %w", teamID, err)
+					}
+					teams = append(teams, *team)
+				}
+			}
+			for _, t := range teams {
+				fmt.Println(formatTeam(t))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&flagAll, "all", "a", false, "List every team instead of the ones in pagerduty.teams")
+	return cmd
+}
+
+func newTeamsShowCmd(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <team>",
+		Short: "Show the members of a team and what it owns",
+		Long:  "Show the members of a team with their roles, and the schedules, escalation policies and services it owns.\n\n" + teamsLong,
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logrus.Debugf("Running teams show command")
+			ctx := context.Background()
+			client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+			team, err := lookupTeam(ctx, client, strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			members, err := client.ListTeamMembersPaginated(ctx, team.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get members of team %q: %w", team.Name, err)
+			}
+			schedules, err := listSchedules(ctx, client, pagerduty.ListSchedulesOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to list schedules: %w", err)
+			}
+			policies, err := listEscalationPolicies(ctx, client, pagerduty.ListEscalationPoliciesOptions{TeamIDs: []string{team.ID}})
+			if err != nil {
+				return fmt.Errorf("failed to get escalation policies of team %q: %w", team.Name, err)
+			}
+			services, err := client.ListServicesPaginated(ctx, pagerduty.ListServiceOptions{TeamIDs: []string{team.ID}})
+			if err != nil {
+				return fmt.Errorf("failed to get services of team %q: %w", team.Name, err)
+			}
+
+			fmt.Printf(ansi.Bold("Name        :")+" %s\n", ansi.ToURL(team.Name, team.HTMLURL))
+			fmt.Printf(ansi.Bold("ID          :")+" %s\n", team.ID)
+			fmt.Printf(ansi.Bold("Description :")+" %s\n", team.Description)
+			fmt.Printf(ansi.Bold("Members     :")+" %d\n", len(members))
+			for _, m := range members {
+				fmt.Printf("  %-10s %s\n", m.Role, ansi.ToURL(m.User.Summary, m.User.HTMLURL))
+			}
+			fmt.Print(ansi.Bold("Schedules   :\n"))
+			for _, s := range schedules {
+				if teamOwnsSchedule(team.ID, s) {
+					fmt.Printf("  %s [ID: %s]\n", ansi.ToURL(s.Name, s.HTMLURL), s.ID)
+				}
+			}
+			fmt.Print(ansi.Bold("Policies    :\n"))
+			for _, ep := range policies {
+				fmt.Printf("  %s [ID: %s]\n", ansi.ToURL(ep.Name, ep.HTMLURL), ep.ID)
+			}
+			fmt.Print(ansi.Bold("Services    :\n"))
+			for _, s := range services {
+				fmt.Printf("  %s [ID: %s] %s\n", ansi.ToURL(s.Name, s.HTMLURL), s.ID, s.Status)
+			}
+			return nil
+		},
+	}
+}
+
+func newTeamsAddMemberCmd(cfg *config.Config) *cobra.Command {
+	var (
+		flagRole string
+		flagYes  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "add-member <team> <user>",
+		Short: "Add a user to a team, or change their role",
+		Long:  "Add a user to a team, or change their role if they already are a member.\n\n" + teamsLong,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logrus.Debugf("Running teams add-member command")
+			ctx := context.Background()
+			role, err := parseTeamRole(flagRole)
+			if err != nil {
+				return err
+			}
+			client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+			team, err := lookupTeam(ctx, client, args[0])
+			if err != nil {
+				return err
+			}
+			user, err := lookupUser(ctx, client, args[1], true)
+			if err != nil {
+				return err
+			}
+			if !flagYes {
+				ok, err := confirm(fmt.Sprintf("Do you want to add %s <%s> to the team %q as %s?", user.Name, user.Email, team.Name, role))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Printf("\nAborting\n")
+					return nil
+				}
+			}
+			if err := client.AddUserToTeamWithContext(ctx, pagerduty.AddUserToTeamOptions{TeamID: team.ID, UserID: user.ID, Role: role}); err != nil {
+				return fmt.Errorf("failed to add %s to team %q: %w", user.Name, team.Name, err)
+			}
+			fmt.Printf("Added %s to %s as %s\n", user.Name, team.Name, role)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&flagRole, "role", "r", string(pagerduty.TeamUserRoleResponder), "Role of the user in the team: observer, responder or manager")
+	cmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "Do not ask for confirmation")
+	return cmd
+}
+
+func newTeamsRemoveMemberCmd(cfg *config.Config) *cobra.Command {
+	var flagYes bool
+	cmd := &cobra.Command{
+		Use:   "remove-member <team> <user>",
+		Short: "Remove a user from a team",
+		Long:  "Remove a user from a team.\n\n" + teamsLong,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logrus.Debugf("Running teams remove-member command")
+			ctx := context.Background()
+			client := pagerduty.NewClient(cfg.PagerDuty.UserToken)
+			team, err := lookupTeam(ctx, client, args[0])
+			if err != nil {
+				return err
+			}
+			user, err := lookupUser(ctx, client, args[1], true)
+			if err != nil {
+				return err
+			}
+			if !flagYes {
+				ok, err := confirm(fmt.Sprintf("Do you want to remove %s <%s> from the team %q?", user.Name, user.Email, team.Name))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Printf("\nAborting\n")
+					return nil
+				}
+			}
+			if err := client.RemoveUserFromTeamWithContext(ctx, team.ID, user.ID); err != nil {
+				return fmt.Errorf("failed to remove %s from team %q: %w", user.Name, team.Name, err)
+			}
+			fmt.Printf("Removed %s from %s\n", user.Name, team.Name)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&flagYes, "yes", "y", false, "Do not ask for confirmation")
+	return cmd
+}
+
+// formatTeam formats a team as a line of `teams list`.
+func formatTeam(t pagerduty.Team) string {
+	line := fmt.Sprintf("%s [ID: %s]", ansi.ToURL(t.Name, t.HTMLURL), t.ID)
+	if t.Description != "" {
+		line += " " + t.Description
+	}
+	return line
+}
+
+// parseTeamRole parses the role of a team member, ignoring case and
+// surrounding spaces.
+func parseTeamRole(s string) (pagerduty.TeamUserRole, error) {
+	role := pagerduty.TeamUserRole(strings.ToLower(strings.TrimSpace(s)))
+	switch role {
+	case pagerduty.TeamUserRoleObserver, pagerduty.TeamUserRoleResponder, pagerduty.TeamUserRoleManager:
+		return role, nil
+	}
+	return "", fmt.Errorf("invalid role %q, must be one of observer, responder, manager", s)
+}
+
+// teamOwnsSchedule reports whether the schedule belongs to the team.
+func teamOwnsSchedule(teamID string, s pagerduty.Schedule) bool {
+	for _, t := range s.Teams {
+		if t.ID == teamID {
+			return true
+		}
+	}
+	return false
+}
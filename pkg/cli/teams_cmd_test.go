@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestParseTeamRole(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    pagerduty.TeamUserRole
+		wantErr bool
+	}{
+		{in: "observer", want: pagerduty.TeamUserRoleObserver},
+		{in: "responder", want: pagerduty.TeamUserRoleResponder},
+		{in: "manager", want: pagerduty.TeamUserRoleManager},
+		{in: " Manager ", want: pagerduty.TeamUserRoleManager},
+		{in: "admin", wantErr: true},
+		{in: "", wantErr: true},
+	} {
+		got, err := parseTeamRole(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseTeamRole(%q): got error %v, want error %v", tc.in, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("parseTeamRole(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestTeamOwnsSchedule(t *testing.T) {
+	sched := func(teamIDs ...string) pagerduty.Schedule {
+		var s pagerduty.Schedule
+		for _, teamID := range teamIDs {
+			s.Teams = append(s.Teams, pagerduty.APIObject{ID: teamID})
+		}
+		return s
+	}
+	for _, tc := range []struct {
+		name     string
+		schedule pagerduty.Schedule
+		want     bool
+	}{
+		{name: "owned", schedule: sched("PTEAM01"), want: true},
+		{name: "shared with another team", schedule: sched("PTEAM02", "PTEAM01"), want: true},
+		{name: "other team", schedule: sched("PTEAM02")},
+		{name: "no team", schedule: sched()},
+	} {
+		if got := teamOwnsSchedule("PTEAM01", tc.schedule); got != tc.want {
+			t.Errorf("%s: teamOwnsSchedule = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -2,9 +2,11 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/sirupsen/logrus"
+	str2duration "github.com/xhit/go-str2duration/v2"
 )
 
 var (
@@ -91,11 +93,77 @@ type OncallConfig struct {
 	// term in that group (e.g. ["k8s", "kubernetes"]). Merged with a small
 	// built-in set. Comparison ignores case and separators.
 	Synonyms [][]string `mapstructure:"synonyms"`
+	// OverrideChecks configures the safety checks run by `oncall override`
+	// before creating an override.
+	OverrideChecks OverrideChecksConfig `mapstructure:"override_checks"`
+}
+
+// OverrideChecksConfig configures the safety checks run before creating an
+// override. Empty values fall back to the Default* constants below.
+type OverrideChecksConfig struct {
+	// NightStart and NightEnd delimit the night hours (HH:MM, in the time
+	// zone of the user taking the override). Overrides falling in the night
+	// cause a warning.
+	NightStart string `mapstructure:"night_start"`
+	NightEnd   string `mapstructure:"night_end"`
+	// MinDuration is the shortest override allowed without --force, in Go
+	// duration format plus days (e.g. "30m", "1d").
+	MinDuration string `mapstructure:"min_duration"`
+}
+
+const (
+	DefaultOverrideNightStart  = "22:00"
+	DefaultOverrideNightEnd    = "07:00"
+	DefaultOverrideMinDuration = "30m"
+)
+
+// NightHours returns the configured night start and end, or the defaults.
+func (o *OverrideChecksConfig) NightHours() (string, string) {
+	start, end := o.NightStart, o.NightEnd
+	if start == "" {
+		start = DefaultOverrideNightStart
+	}
+	if end == "" {
+		end = DefaultOverrideNightEnd
+	}
+	return start, end
+}
+
+// MinOverrideDuration returns the configured minimum override duration, or
+// the default.
+func (o *OverrideChecksConfig) MinOverrideDuration() time.Duration {
+	md := o.MinDuration
+	if md == "" {
+		md = DefaultOverrideMinDuration
+	}
+	// validated in Validate, the default is always valid
+	d, _ := str2duration.ParseDuration(md)
+	return d
+}
+
+func (o *OverrideChecksConfig) Validate(cfg *Config) error {
+	for name, v := range map[string]string{"night_start": o.NightStart, "night_end": o.NightEnd} {
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", v); err != nil {
+			return fmt.Errorf("`%s` must be in HH:MM format, got %q", name, v)
+		}
+	}
+	if o.MinDuration != "" {
+		if _, err := str2duration.ParseDuration(o.MinDuration); err != nil {
+			return fmt.Errorf("invalid `min_duration` %q: %w", o.MinDuration, err)
+		}
+	}
+	return nil
 }
 
 // OncallShortlistEntry is a curated component-to-schedule mapping used by the
 // `oncall shortlist` subcommand. Each entry resolves to a PagerDuty schedule
-// either directly by `schedule_id` or by searching schedules with `query`.
+// either directly by `schedule_id` or by searching schedules with `query`,
+// and/or to the levels of an escalation policy, referenced directly by
+// `escalation_policy_id` or through a service by `service_id` or
+// `service_query`.
 type OncallShortlistEntry struct {
 	// Name is the human-friendly component/team label shown in the output.
 	Name string `mapstructure:"name"`
@@ -107,21 +175,82 @@ type OncallShortlistEntry struct {
 	// ScheduleID pins the entry to a specific PagerDuty schedule ID, skipping
 	// the search. Takes precedence over Query when both are set.
 	ScheduleID string `mapstructure:"schedule_id"`
+	// EscalationPolicyID references an escalation policy whose levels (e.g.
+	// primary and secondary) are shown with who is oncall for each of them.
+	EscalationPolicyID string `mapstructure:"escalation_policy_id"`
+	// ServiceID references a service, resolved to its escalation policy.
+	// Ignored if EscalationPolicyID is set.
+	ServiceID string `mapstructure:"service_id"`
+	// ServiceQuery is a free-text service search, resolved to the escalation
+	// policy of the matching service. Ignored if ServiceID or
+	// EscalationPolicyID are set.
+	ServiceQuery string `mapstructure:"service_query"`
+	// PageVia selects how `oncall page` pages this entry: "service" creates
+	// the incident on the service, notifying its escalation policy, and
+	// "escalation_policy" also assigns the incident to the entry's escalation
+	// policy. Defaults to "escalation_policy" when EscalationPolicyID is set,
+	// "service" otherwise.
+	PageVia string `mapstructure:"page_via"`
+	// PageServiceID is the service on which `oncall page` creates incidents.
+	// Defaults to the entry's service, or to the service using the entry's
+	// escalation policy or schedules.
+	PageServiceID string `mapstructure:"page_service_id"`
 	// Aliases are additional keywords this entry matches against (beyond Name
 	// and Component), e.g. ["k8s", "control-plane"]. Matched case- and
 	// separator-insensitively.
 	Aliases []string `mapstructure:"aliases"`
 }
 
+// Values of OncallShortlistEntry.PageVia.
+const (
+	PageViaService          = "service"
+	PageViaEscalationPolicy = "escalation_policy"
+)
+
+// PageTarget returns how `oncall page` pages the entry, PageViaService or
+// PageViaEscalationPolicy.
+func (e *OncallShortlistEntry) PageTarget() string {
+	if e.PageVia != "" {
+		return e.PageVia
+	}
+	if e.EscalationPolicyID != "" {
+		return PageViaEscalationPolicy
+	}
+	return PageViaService
+}
+
+// HasSchedule reports whether the entry references schedules directly.
+func (e *OncallShortlistEntry) HasSchedule() bool {
+	return e.ScheduleID != "" || e.Query != ""
+}
+
+// HasEscalationPolicy reports whether the entry references an escalation
+// policy, directly or through a service.
+func (e *OncallShortlistEntry) HasEscalationPolicy() bool {
+	return e.EscalationPolicyID != "" || e.ServiceID != "" || e.ServiceQuery != ""
+}
+
 func (o *OncallConfig) Validate(cfg *Config) error {
 	for idx, e := range o.Shortlist {
 		if e.Name == "" {
 			return fmt.Errorf("`oncall.shortlist` entry at index %d is missing a `name`", idx)
 		}
-		if e.Query == "" && e.ScheduleID == "" {
-			return fmt.Errorf("`oncall.shortlist` entry %q must set either `query` or `schedule_id`", e.Name)
+		if !e.HasSchedule() && !e.HasEscalationPolicy() {
+			return fmt.Errorf("`oncall.shortlist` entry %q must set one of `query`, `schedule_id`, `escalation_policy_id`, `service_id` or `service_query`", e.Name)
+		}
+		switch e.PageVia {
+		case "", PageViaService:
+		case PageViaEscalationPolicy:
+			if !e.HasEscalationPolicy() {
+				return fmt.Errorf("`oncall.shortlist` entry %q has `page_via: %s` but no escalation policy or service", e.Name, e.PageVia)
+			}
+		default:
+			return fmt.Errorf("`oncall.shortlist` entry %q has invalid `page_via` %q, must be %q or %q", e.Name, e.PageVia, PageViaService, PageViaEscalationPolicy)
 		}
 	}
+	if err := o.OverrideChecks.Validate(cfg); err != nil {
+		return fmt.Errorf("invalid `oncall.override_checks`: %w", err)
+	}
 	return nil
 }
 